@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/relayer/relayer"
+)
+
+// maxAPIErrors is how many of the latest errors of the relay loop the API reports
+const maxAPIErrors = 20
+
+// daemon is the state of a running start shared with its API. The chains share
+// their path ends with the relay loop, so anything using them is run on the loop
+// through requests. Paths are paused by their index in the config.
+type daemon struct {
+	requests chan func()
+	trigger  chan struct{}
+	progress *relayer.ProgressStore
+
+	mtx       sync.Mutex
+	paused    map[int]bool
+	started   time.Time
+	lastRelay time.Time
+	errs      []loopError
+	ready     readiness
+}
+
+// loopError is an error of the relay loop
+type loopError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+func newDaemon(progress *relayer.ProgressStore) *daemon {
+	return &daemon{
+		requests: make(chan func()),
+		trigger:  make(chan struct{}, 1),
+		progress: progress,
+		paused:   make(map[int]bool),
+		started:  time.Now(),
+	}
+}
+
+// unpaused returns the configured paths among paths that haven't been paused
+// through the API
+func (d *daemon) unpaused(paths relayer.Paths) relayer.Paths {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var out relayer.Paths
+	for i, p := range config.Paths {
+		if d.paused[i] {
+			continue
+		}
+		for _, q := range paths {
+			if p == q {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// setPaused pauses or resumes relaying on the path with the given index
+func (d *daemon) setPaused(index int, paused bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.paused[index] = paused
+}
+
+// relayed records the end of a relay round
+func (d *daemon) relayed() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.lastRelay = time.Now()
+}
+
+// sinceRelay returns how long ago the last relay round finished, or start began
+// if none has yet
+func (d *daemon) sinceRelay() time.Duration {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.lastRelay.IsZero() {
+		return time.Since(d.started)
+	}
+	return time.Since(d.lastRelay)
+}
+
+// recordErr keeps err among the latest errors of the relay loop
+func (d *daemon) recordErr(err error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.errs = append(d.errs, loopError{Time: time.Now(), Error: err.Error()})
+	if len(d.errs) > maxAPIErrors {
+		d.errs = d.errs[len(d.errs)-maxAPIErrors:]
+	}
+}
+
+// do runs fn on the relay loop and returns its result, or an error if the request
+// is cancelled before the loop picks it up
+func (d *daemon) do(r *http.Request, fn func() (interface{}, error)) (interface{}, error) {
+	var (
+		out  interface{}
+		err  error
+		done = make(chan struct{})
+	)
+
+	select {
+	case d.requests <- func() { out, err = fn(); close(done) }:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+
+	<-done
+	return out, err
+}
+
+// chainStatus is the state of a chain reported by the API
+type chainStatus struct {
+	ChainID    string                `json:"chain-id"`
+	RPCAddr    string                `json:"rpc-addr"`
+	Height     int64                 `json:"height"`
+	LiteHeight int64                 `json:"lite-height"`
+	Halted     string                `json:"halted,omitempty"`
+	Balance    relayer.BalanceStatus `json:"balance"`
+	Errors     []string              `json:"errors,omitempty"`
+}
+
+// pathStatus is the state of a path reported by the API
+type pathStatus struct {
+	Index  int          `json:"index"`
+	Path   relayer.Path `json:"path"`
+	Paused bool         `json:"paused"`
+}
+
+// status is the state of the relayer reported by the API
+type status struct {
+	Chains    []chainStatus `json:"chains"`
+	Paths     []pathStatus  `json:"paths"`
+	LastRelay time.Time     `json:"last-relay"`
+	Errors    []loopError   `json:"errors"`
+}
+
+// status queries the chains, it must run on the relay loop
+func (d *daemon) status() status {
+	out := status{Chains: []chainStatus{}, Paths: []pathStatus{}}
+	for _, c := range config.c {
+		cs := chainStatus{ChainID: c.ChainID, RPCAddr: c.Client.ActiveAddr(), Balance: c.BalanceStatus()}
+		var err error
+		if cs.Height, err = c.QueryLatestHeight(); err != nil {
+			cs.Errors = append(cs.Errors, err.Error())
+		}
+		if cs.LiteHeight, err = c.GetLatestLiteHeight(); err != nil {
+			cs.Errors = append(cs.Errors, err.Error())
+		}
+		if err = c.Halted(); err != nil {
+			cs.Halted = err.Error()
+		}
+		out.Chains = append(out.Chains, cs)
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for i, p := range config.Paths {
+		out.Paths = append(out.Paths, pathStatus{Index: i, Path: p, Paused: d.paused[i]})
+	}
+	out.LastRelay = d.lastRelay
+	out.Errors = append([]loopError{}, d.errs...)
+	return out
+}
+
+// serveAPI serves the API on addr, requiring token as a bearer token if set:
+//
+//	GET  /status                        chains, paths and the latest errors
+//	GET  /paths/{index}/pending         packets and acks waiting to be relayed
+//	GET  /paths/{index}/progress        relay progress recorded for the path
+//	POST /paths/{index}/pause           stop relaying on the path
+//	POST /paths/{index}/resume          resume relaying on the path
+//	POST /paths/{index}/update-clients  update the clients on both ends of the path
+//	POST /relay                         start a relay round now
+func (d *daemon) serveAPI(addr, token string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", d.handleStatus)
+	mux.HandleFunc("/relay", d.handleRelay)
+	mux.HandleFunc("/paths/", d.handlePath)
+
+	logger.Info("serving API", "addr", addr)
+	if err := http.ListenAndServe(addr, requireToken(token, mux)); err != nil {
+		logger.Error("API server stopped", "addr", addr, "err", err)
+	}
+}
+
+func (d *daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+		return
+	}
+	out, err := d.do(r, func() (interface{}, error) { return d.status(), nil })
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (d *daemon) handleRelay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+		return
+	}
+
+	// a round that is already due covers this one
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "relay round triggered"})
+}
+
+// pathEndpoints are the methods of the endpoints under /paths/{index}/
+var pathEndpoints = map[string]string{
+	"pending":        http.MethodGet,
+	"progress":       http.MethodGet,
+	"pause":          http.MethodPost,
+	"resume":         http.MethodPost,
+	"update-clients": http.MethodPost,
+}
+
+func (d *daemon) handlePath(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/paths/"), "/"), "/")
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint %s", r.URL.Path))
+		return
+	}
+
+	p, err := config.path(parts[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	index, _ := strconv.Atoi(parts[0])
+
+	method, ok := pathEndpoints[parts[1]]
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint %s", r.URL.Path))
+		return
+	case r.Method != method:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+		return
+	}
+
+	var out interface{}
+	switch parts[1] {
+	case "pending":
+		out, err = d.do(r, func() (interface{}, error) { return pendingOnPath(p) })
+	case "progress":
+		out, err = d.progress.Progress(p)
+	case "pause":
+		d.setPaused(index, true)
+		logger.Info("paused path", "path", p)
+		out = pathStatus{Index: index, Path: p, Paused: true}
+	case "resume":
+		d.setPaused(index, false)
+		logger.Info("resumed path", "path", p)
+		out = pathStatus{Index: index, Path: p, Paused: false}
+	case "update-clients":
+		out, err = d.do(r, func() (interface{}, error) { return updateClientsOnPath(p) })
+	}
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// pendingOnPath returns what is waiting to be relayed in both directions of p
+func pendingOnPath(p relayer.Path) ([]*relayer.UnrelayedSequences, error) {
+	src, dst, err := config.setPathChains(p)
+	if err != nil {
+		return nil, err
+	}
+
+	srcToDst, err := relayer.QueryUnrelayed(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	dstToSrc, err := relayer.QueryUnrelayed(dst, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*relayer.UnrelayedSequences{srcToDst, dstToSrc}, nil
+}
+
+// updateClientsOnPath updates the clients on both ends of p with the latest
+// headers of the chains they track
+func updateClientsOnPath(p relayer.Path) ([]sdk.TxResponse, error) {
+	src, dst, err := config.setPathChains(p)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := relayer.UpdatesWithHeaders(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []sdk.TxResponse
+	for _, c := range []struct{ chain, tracked *relayer.Chain }{{src, dst}, {dst, src}} {
+		res, err := c.chain.SendMsg(c.chain.UpdateClient(headers[c.tracked.ChainID]))
+		if err != nil {
+			return out, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// requireToken wraps next to reject requests without token as their bearer
+// token, unless token is empty
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopback returns true if addr, a host:port, only listens on the loopback interface
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to write API response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -17,31 +17,302 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
+	"time"
 
 	"github.com/cosmos/cosmos-sdk/client/flags"
 	"github.com/cosmos/relayer/relayer"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/log"
 	"gopkg.in/yaml.v2"
 )
 
 // Config represents the config file for the relayer
 type Config struct {
-	Global GlobalConfig   `yaml:"global" json:"global"`
-	Chains []ChainConfig  `yaml:"chains" json:"chains"`
-	Paths  []relayer.Path `yaml:"paths" json:"paths"`
+	Version int            `yaml:"version" json:"version"`
+	Global  GlobalConfig   `yaml:"global" json:"global"`
+	Chains  []ChainConfig  `yaml:"chains" json:"chains"`
+	Paths   []relayer.Path `yaml:"paths" json:"paths"`
 
 	c relayer.Chains
 }
 
 // GlobalConfig describes any global relayer settings
 type GlobalConfig struct {
-	Strategy      string `yaml:"strategy" json:"strategy"`
-	Timeout       string `yaml:"timeout" json:"timeout"`
-	LiteCacheSize int    `yaml:"lite-cache-size" json:"lite-cache-size"`
+	Strategy               string `yaml:"strategy" json:"strategy"`
+	RelayInterval          string `yaml:"relay-interval,omitempty" json:"relay-interval,omitempty"`
+	LiteUpdateInterval     string `yaml:"lite-update-interval,omitempty" json:"lite-update-interval,omitempty"`
+	RPCTimeout             string `yaml:"rpc-timeout,omitempty" json:"rpc-timeout,omitempty"`
+	TxConfirmationTimeout  string `yaml:"tx-confirmation-timeout,omitempty" json:"tx-confirmation-timeout,omitempty"`
+	RPCHealthCheckInterval string `yaml:"rpc-health-check-interval,omitempty" json:"rpc-health-check-interval,omitempty"`
+	LiteCacheSize          int    `yaml:"lite-cache-size" json:"lite-cache-size"`
+
+	// EventDriven makes start relay as soon as IBC events are seen on a chain's
+	// websocket, the relay interval is kept as a polling fallback
+	EventDriven bool `yaml:"event-driven,omitempty" json:"event-driven,omitempty"`
+
+	// EventStaleTimeout is how long a chain's event subscription may go without
+	// a new block before it is made again
+	EventStaleTimeout string `yaml:"event-stale-timeout,omitempty" json:"event-stale-timeout,omitempty"`
+
+	// ClientUpdateThreshold is the fraction of a client's trusting period that may
+	// pass since its last update before start updates it, even without traffic
+	ClientUpdateThreshold     float64 `yaml:"client-update-threshold,omitempty" json:"client-update-threshold,omitempty"`
+	ClientExpiryCheckInterval string  `yaml:"client-expiry-check-interval,omitempty" json:"client-expiry-check-interval,omitempty"`
+
+	// CheckMisbehaviour makes start compare the consensus states stored by the
+	// clients on every path with the lite clients and submit evidence on conflict
+	CheckMisbehaviour bool `yaml:"check-misbehaviour,omitempty" json:"check-misbehaviour,omitempty"`
+
+	// BalanceCheckInterval is how often start checks the relayer's balance on
+	// every chain against its min-balance
+	BalanceCheckInterval string `yaml:"balance-check-interval,omitempty" json:"balance-check-interval,omitempty"`
+
+	// MetricsAddr is the address start serves Prometheus metrics on at /metrics,
+	// e.g. localhost:9100. Metrics aren't served if it is empty.
+	MetricsAddr string `yaml:"metrics-addr,omitempty" json:"metrics-addr,omitempty"`
+
+	// APIAddr is the address start serves its status and control API on, e.g.
+	// localhost:5183. The API isn't served if it is empty.
+	APIAddr string `yaml:"api-addr,omitempty" json:"api-addr,omitempty"`
+
+	// APIToken must be sent as a bearer token with every API request, it is
+	// required to serve the API on an address other than loopback
+	APIToken string `yaml:"api-token,omitempty" json:"api-token,omitempty"`
+
+	// HealthAddr is the address start serves its liveness and readiness probes on
+	HealthAddr string `yaml:"health-addr,omitempty" json:"health-addr,omitempty"`
+
+	// RelayStallThreshold is how long the relay loop may go without finishing a
+	// round before /healthz fails, it must be longer than the relay interval
+	RelayStallThreshold string `yaml:"relay-stall-threshold,omitempty" json:"relay-stall-threshold,omitempty"`
+
+	// LogLevel is debug, info or error, LogFormat text or json. Logs are written
+	// to LogFile, or stdout if it is empty.
+	LogLevel  string `yaml:"log-level,omitempty" json:"log-level,omitempty"`
+	LogFormat string `yaml:"log-format,omitempty" json:"log-format,omitempty"`
+	LogFile   string `yaml:"log-file,omitempty" json:"log-file,omitempty"`
+
+	// Notifications are sent by start about relay events operators should know of
+	Notifications NotificationConfig `yaml:"notifications,omitempty" json:"notifications,omitempty"`
+}
+
+// NotificationConfig configures where start sends notifications
+type NotificationConfig struct {
+	Webhooks  []WebhookConfig `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+	EventFile string          `yaml:"event-file,omitempty" json:"event-file,omitempty"`
+
+	// TxFailureThreshold is how many txs in a row must fail on a chain before
+	// it is notified
+	TxFailureThreshold int `yaml:"tx-failure-threshold,omitempty" json:"tx-failure-threshold,omitempty"`
+}
+
+// WebhookConfig is a URL notifications are POSTed to as JSON
+type WebhookConfig struct {
+	URL     string `yaml:"url" json:"url"`
+	Retries int    `yaml:"retries,omitempty" json:"retries,omitempty"`
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Defaults for the durations in GlobalConfig, used when they are left unset
+const (
+	defaultRelayInterval          = "10s"
+	defaultLiteUpdateInterval     = "5s"
+	defaultRPCTimeout             = "10s"
+	defaultTxConfirmationTimeout  = "30s"
+	defaultRPCHealthCheckInterval = "30s"
+	defaultMaxBlockAge            = "1m"
+	defaultBalanceCheckInterval   = "1m"
+	defaultEventStaleTimeout      = "1m"
+	defaultRelayStallThreshold    = "5m"
+	defaultHealthAddr             = "127.0.0.1:5184"
+
+	defaultClientExpiryCheckInterval = "1m"
+	defaultLogLevel                  = "info"
+	defaultWebhookTimeout            = "10s"
+	defaultWebhookRetries            = 3
+	defaultTxFailureThreshold        = 3
+	defaultClientUpdateThreshold     = 2.0 / 3
+)
+
+// relayInterval returns the period between runs of the relay strategy
+func (g GlobalConfig) relayInterval() (time.Duration, error) {
+	return time.ParseDuration(orDefault(g.RelayInterval, defaultRelayInterval))
+}
+
+// relayStallThreshold returns how long the relay loop may go without finishing a
+// round before it is considered stalled, which must exceed the relay interval
+func (g GlobalConfig) relayStallThreshold(relayInterval time.Duration) (time.Duration, error) {
+	st, err := time.ParseDuration(orDefault(g.RelayStallThreshold, defaultRelayStallThreshold))
+	if err != nil {
+		return 0, err
+	}
+	if st <= relayInterval {
+		return 0, fmt.Errorf("relay-stall-threshold (%s) must be longer than relay-interval (%s)", st, relayInterval)
+	}
+	return st, nil
+}
+
+// apiAddr returns the address the API is served on, empty if it isn't. The API
+// is only served beyond loopback if an API token is set.
+func (g GlobalConfig) apiAddr() (string, error) {
+	if g.APIAddr != "" && g.APIToken == "" && !isLoopback(g.APIAddr) {
+		return "", fmt.Errorf("api-addr (%s) isn't a loopback address, set api-token to serve the API on it", g.APIAddr)
+	}
+	return g.APIAddr, nil
+}
+
+// rpcHealthCheckInterval returns the period between health checks of each chain's RPC endpoints
+func (g GlobalConfig) rpcHealthCheckInterval() (time.Duration, error) {
+	return time.ParseDuration(orDefault(g.RPCHealthCheckInterval, defaultRPCHealthCheckInterval))
+}
+
+// balanceCheckInterval returns the period between checks of the relayer's balance on each chain
+func (g GlobalConfig) balanceCheckInterval() (time.Duration, error) {
+	return time.ParseDuration(orDefault(g.BalanceCheckInterval, defaultBalanceCheckInterval))
+}
+
+// eventStaleTimeout returns how long an event subscription may go without a new
+// block before it is made again
+func (g GlobalConfig) eventStaleTimeout() (time.Duration, error) {
+	return time.ParseDuration(orDefault(g.EventStaleTimeout, defaultEventStaleTimeout))
+}
+
+// clientExpiryCheckInterval returns the period between checks of the clients on
+// every path for expiry
+func (g GlobalConfig) clientExpiryCheckInterval() (time.Duration, error) {
+	return time.ParseDuration(orDefault(g.ClientExpiryCheckInterval, defaultClientExpiryCheckInterval))
+}
+
+// clientUpdateThreshold returns the fraction of the trusting period after which
+// clients are updated
+func (g GlobalConfig) clientUpdateThreshold() (float64, error) {
+	switch {
+	case g.ClientUpdateThreshold == 0:
+		return defaultClientUpdateThreshold, nil
+	case g.ClientUpdateThreshold < 0 || g.ClientUpdateThreshold >= 1:
+		return 0, fmt.Errorf("client-update-threshold must be between 0 and 1, got %v", g.ClientUpdateThreshold)
+	}
+	return g.ClientUpdateThreshold, nil
+}
+
+// newLogger returns the logger configured by the log settings. Only the daemon,
+// start, writes to stdout or the log file, opened for appending, other commands
+// log to stderr so their output stays clean.
+func (g GlobalConfig) newLogger(daemon bool) (log.Logger, error) {
+	w := io.Writer(os.Stderr)
+	switch {
+	case daemon && g.LogFile != "":
+		f, err := os.OpenFile(g.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w = f
+	case daemon:
+		w = os.Stdout
+	}
+	return relayer.NewLogger(w, orDefault(g.LogFormat, relayer.LogFormatText), orDefault(g.LogLevel, defaultLogLevel))
+}
+
+// newNotifier returns the notifier sending to the configured webhooks and event
+// file, failures to send are logged to logger
+func (n NotificationConfig) newNotifier(logger log.Logger) (*relayer.Notifier, error) {
+	var sinks []relayer.NotificationSink
+	for _, w := range n.Webhooks {
+		if w.URL == "" {
+			return nil, fmt.Errorf("webhook url must be set")
+		}
+		timeout, err := time.ParseDuration(orDefault(w.Timeout, defaultWebhookTimeout))
+		if err != nil {
+			return nil, err
+		}
+		retries := w.Retries
+		if retries == 0 {
+			retries = defaultWebhookRetries
+		}
+		sinks = append(sinks, relayer.NewWebhookSink(w.URL, retries, timeout))
+	}
+
+	if n.EventFile != "" {
+		sink, err := relayer.NewEventFileSink(n.EventFile)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	threshold := n.TxFailureThreshold
+	if threshold == 0 {
+		threshold = defaultTxFailureThreshold
+	}
+	return relayer.NewNotifier(logger, threshold, sinks...), nil
+}
+
+// orDefault returns the first of values that is set
+func orDefault(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// path returns the configured path with the given index
+func (c *Config) path(index string) (relayer.Path, error) {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(c.Paths) {
+		return relayer.Path{}, errPathNotFound(index)
+	}
+	return c.Paths[i], nil
+}
+
+// pathChains returns the chains at either end of the configured path with the
+// given index, with the path set on both
+func (c *Config) pathChains(index string) (src, dst *relayer.Chain, err error) {
+	p, err := c.path(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.setPathChains(p)
+}
+
+// pathBetween returns the chains with the given IDs, with the path set on both
+// to the first configured path between them, taken in either direction
+func (c *Config) pathBetween(srcID, dstID string) (src, dst *relayer.Chain, err error) {
+	for _, p := range c.Paths {
+		switch {
+		case p.Src.ChainID == srcID && p.Dst.ChainID == dstID:
+			return c.setPathChains(p)
+		case p.Src.ChainID == dstID && p.Dst.ChainID == srcID:
+			return c.setPathChains(p.Reverse())
+		}
+	}
+	return nil, nil, errNoPathBetween(srcID, dstID)
+}
+
+// setPathChains returns the chains at either end of p, with the path set on both
+func (c *Config) setPathChains(p relayer.Path) (src, dst *relayer.Chain, err error) {
+	if src, err = c.c.GetChain(p.Src.ChainID); err != nil {
+		return nil, nil, err
+	}
+	if dst, err = c.c.GetChain(p.Dst.ChainID); err != nil {
+		return nil, nil, err
+	}
+
+	if err = src.SetNewFullPath(p.Src.ClientID, p.Src.ConnectionID, p.Src.ChannelID, p.Src.PortID); err != nil {
+		return nil, nil, err
+	}
+	if err = dst.SetNewFullPath(p.Dst.ClientID, p.Dst.ConnectionID, p.Dst.ChannelID, p.Dst.PortID); err != nil {
+		return nil, nil, err
+	}
+
+	return src, dst, nil
 }
 
 // ChainConfig describes the config necessary for an individual chain
@@ -54,21 +325,79 @@ type ChainConfig struct {
 	Gas            uint64  `yaml:"gas,omitempty" json:"gas,omitempty"`
 	GasAdjustment  float64 `yaml:"gas-adjustment,omitempty" json:"gas-adjustment,omitempty"`
 	GasPrices      string  `yaml:"gas-prices,omitempty" json:"gas-prices,omitempty"`
+	Fees           string  `yaml:"fees,omitempty" json:"fees,omitempty"`
+	MaxFee         string  `yaml:"max-fee,omitempty" json:"max-fee,omitempty"`
+	FeeBump        float64 `yaml:"fee-bump,omitempty" json:"fee-bump,omitempty"`
+	MinBalance     string  `yaml:"min-balance,omitempty" json:"min-balance,omitempty"`
 	DefaultDenom   string  `yaml:"default-denom,omitempty" json:"default-denom,omitempty"`
 	Memo           string  `yaml:"memo,omitempty" json:"memo,omitempty"`
 	TrustingPeriod string  `yaml:"trusting-period" json:"trusting-period"`
+
+	// LiteUpdateInterval overrides global.lite-update-interval for this chain
+	LiteUpdateInterval string `yaml:"lite-update-interval,omitempty" json:"lite-update-interval,omitempty"`
+
+	// BackupRPCAddrs are tried in order when rpc-addr is unhealthy, the relayer
+	// switches back to rpc-addr once it recovers
+	BackupRPCAddrs []string `yaml:"backup-rpc-addrs,omitempty" json:"backup-rpc-addrs,omitempty"`
+
+	// MaxBlockAge is how old the latest block of an RPC endpoint may be before
+	// it is considered unhealthy
+	MaxBlockAge string `yaml:"max-block-age,omitempty" json:"max-block-age,omitempty"`
+
+	// Witnesses are RPC addresses of full nodes, independent of rpc-addr, that
+	// the lite client cross-checks headers against to detect forks
+	Witnesses []string `yaml:"witnesses,omitempty" json:"witnesses,omitempty"`
+
+	// MaxMsgsPerTx and MaxTxBytes limit the number of msgs in a tx and their
+	// encoded size, larger batches are split into several txs. 0 is no limit.
+	MaxMsgsPerTx int `yaml:"max-msgs-per-tx,omitempty" json:"max-msgs-per-tx,omitempty"`
+	MaxTxBytes   int `yaml:"max-tx-bytes,omitempty" json:"max-tx-bytes,omitempty"`
 }
 
-// Called to set the relayer.Chain types on Config
-func setChains(c *Config, home string) error {
+// options returns the options of the chain in the relayer home, with the global
+// settings it uses and the defaults of those left unset
+func (i ChainConfig) options(g GlobalConfig, home string) relayer.ChainOptions {
+	return relayer.ChainOptions{
+		Key:                   i.Key,
+		ChainID:               i.ChainID,
+		RPCAddr:               i.RPCAddr,
+		AccountPrefix:         i.AccountPrefix,
+		Gas:                   i.Gas,
+		GasAdjustment:         i.GasAdjustment,
+		GasPrices:             i.GasPrices,
+		Fees:                  i.Fees,
+		MaxFee:                i.MaxFee,
+		FeeBump:               i.FeeBump,
+		MinBalance:            i.MinBalance,
+		DefaultDenom:          i.DefaultDenom,
+		Memo:                  i.Memo,
+		HomePath:              home,
+		LiteCacheSize:         g.LiteCacheSize,
+		TrustingPeriod:        i.TrustingPeriod,
+		LiteUpdateInterval:    orDefault(i.LiteUpdateInterval, g.LiteUpdateInterval, defaultLiteUpdateInterval),
+		RPCTimeout:            orDefault(g.RPCTimeout, defaultRPCTimeout),
+		TxConfirmationTimeout: orDefault(g.TxConfirmationTimeout, defaultTxConfirmationTimeout),
+		MaxBlockAge:           orDefault(i.MaxBlockAge, defaultMaxBlockAge),
+		MaxMsgsPerTx:          i.MaxMsgsPerTx,
+		MaxTxBytes:            i.MaxTxBytes,
+		BackupRPCAddrs:        i.BackupRPCAddrs,
+		Witnesses:             i.Witnesses,
+	}
+}
+
+// Called to set the relayer.Chain types on Config, logging by the log settings.
+// The log outputs only apply to the daemon, start.
+func setChains(c *Config, home string, daemon bool) error {
+	l, err := c.Global.newLogger(daemon)
+	if err != nil {
+		return err
+	}
+	logger = l
+
 	var out []*relayer.Chain
-	var new = &Config{Global: c.Global, Chains: c.Chains, Paths: c.Paths}
+	var new = &Config{Version: c.Version, Global: c.Global, Chains: c.Chains, Paths: c.Paths}
 	for _, i := range c.Chains {
-		homeDir := path.Join(home, "lite")
-		chain, err := relayer.NewChain(i.Key, i.ChainID, i.RPCAddr,
-			i.AccountPrefix, i.Gas, i.GasAdjustment, i.GasPrices,
-			i.DefaultDenom, i.Memo, homePath, c.Global.LiteCacheSize,
-			i.TrustingPeriod, homeDir, cdc)
+		chain, err := relayer.NewChain(i.options(c.Global, home), cdc, logger)
 		if err != nil {
 			return err
 		}
@@ -79,8 +408,9 @@ func setChains(c *Config, home string) error {
 	return nil
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig(cmd *cobra.Command) error {
+// initConfig reads in config file and ENV variables if set. daemon is set for
+// start, see setChains.
+func initConfig(cmd *cobra.Command, daemon bool) error {
 	home, err := cmd.PersistentFlags().GetString(flags.FlagHome)
 	if err != nil {
 		return err
@@ -98,6 +428,17 @@ func initConfig(cmd *cobra.Command) error {
 				os.Exit(1)
 			}
 
+			// upgrade older config layouts in memory before parsing them
+			file, from, err := migrateConfig(file)
+			if err != nil {
+				fmt.Println("Error migrating config:", err)
+				os.Exit(1)
+			}
+			if from < currentConfigVersion {
+				fmt.Fprintf(os.Stderr, "config at %s is version %d, run `relayer config migrate` to upgrade it to version %d\n",
+					cfgPath, from, currentConfigVersion)
+			}
+
 			// unmarshall them into the struct
 			err = yaml.Unmarshal(file, config)
 			if err != nil {
@@ -106,7 +447,7 @@ func initConfig(cmd *cobra.Command) error {
 			}
 
 			// ensure config has []*relayer.Chain used for all chain operations
-			err = setChains(config, home)
+			err = setChains(config, home, daemon)
 			if err != nil {
 				fmt.Println("Error parsing chain config:", err)
 				os.Exit(1)
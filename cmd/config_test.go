@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func TestChainConfigOptions(t *testing.T) {
+	global := GlobalConfig{LiteUpdateInterval: "30s", TxConfirmationTimeout: "2m"}
+
+	opts := ChainConfig{ChainID: "ibc0"}.options(global, "home")
+	if opts.LiteUpdateInterval != "30s" {
+		t.Errorf("lite update interval = %s, want the global 30s", opts.LiteUpdateInterval)
+	}
+	if opts.TxConfirmationTimeout != "2m" {
+		t.Errorf("tx confirmation timeout = %s, want the global 2m", opts.TxConfirmationTimeout)
+	}
+	if opts.RPCTimeout != defaultRPCTimeout {
+		t.Errorf("rpc timeout = %s, want the default %s", opts.RPCTimeout, defaultRPCTimeout)
+	}
+
+	opts = ChainConfig{ChainID: "ibc0", LiteUpdateInterval: "1s"}.options(GlobalConfig{}, "home")
+	if opts.LiteUpdateInterval != "1s" {
+		t.Errorf("lite update interval = %s, want the chain's 1s", opts.LiteUpdateInterval)
+	}
+	if opts.TxConfirmationTimeout != defaultTxConfirmationTimeout {
+		t.Errorf("tx confirmation timeout = %s, want the default %s", opts.TxConfirmationTimeout, defaultTxConfirmationTimeout)
+	}
+}
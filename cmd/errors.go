@@ -30,3 +30,13 @@ func errKeyDoesntExist(name string) error {
 }
 
 var errInitWrongFlags = errors.New("expected either (--hash/-x & --height) OR --url/-u OR --force/-f, none given")
+
+var errNoLiteCacheSize = errors.New("no number of headers to keep, pass --keep/-k or set global.lite-cache-size")
+
+func errPathNotFound(path string) error {
+	return fmt.Errorf("no path with index %s, see `relayer paths`", path)
+}
+
+func errNoPathBetween(src, dst string) error {
+	return fmt.Errorf("no path between %s and %s, see `relayer paths`", src, dst)
+}
@@ -17,6 +17,10 @@ var (
 	flagForce   = "force"
 	flagFlags   = "flags"
 	flagConfig  = "config"
+	flagKeep    = "keep"
+	flagReset   = "reset"
+	flagReverse = "reverse"
+	flagRelay   = "relay"
 )
 
 func liteFlags(cmd *cobra.Command) *cobra.Command {
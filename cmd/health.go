@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cosmos/relayer/relayer"
+)
+
+// readiness is the result of the latest readiness check of the chains
+type readiness struct {
+	checked  time.Time
+	notReady map[string]string
+}
+
+// checkReady checks whether every chain is ready to be relayed on. It runs on the
+// relay loop after every round, /readyz serves the result of the latest check.
+func (d *daemon) checkReady(chains relayer.Chains) {
+	notReady := make(map[string]string)
+	for _, c := range chains {
+		if err := c.Ready(); err != nil {
+			logger.Debug("chain not ready", "chain-id", c.ChainID, "err", err)
+			notReady[c.ChainID] = notReadyReason(err)
+		}
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.ready = readiness{checked: time.Now(), notReady: notReady}
+}
+
+// notReadyReason returns the label /readyz reports for a chain that isn't ready,
+// the error itself is only logged
+func notReadyReason(err error) string {
+	switch {
+	case errors.Is(err, relayer.ErrRPCUnavailable):
+		return "rpc_unavailable"
+	case errors.Is(err, relayer.ErrLiteNotInitialized):
+		return "lite_not_initialized"
+	case errors.Is(err, relayer.ErrChainHalted):
+		return "halted"
+	default:
+		return "lite_client_error"
+	}
+}
+
+// serveHealth serves the liveness and readiness endpoints on addr:
+//
+//	GET /healthz  fails once no relay round has finished for stallThreshold
+//	GET /readyz   fails while any chain isn't ready to be relayed on
+func (d *daemon) serveHealth(addr string, stallThreshold time.Duration) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if since := d.sinceRelay(); since > stallThreshold {
+			writeError(w, http.StatusServiceUnavailable, fmt.Errorf("no relay round has finished for %s", since.Round(time.Second)))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		d.mtx.Lock()
+		ready := d.ready
+		d.mtx.Unlock()
+
+		switch {
+		case ready.checked.IsZero():
+			writeError(w, http.StatusServiceUnavailable, fmt.Errorf("no relay round has finished yet"))
+		case len(ready.notReady) > 0:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+				"status": "not ready", "checked-at": ready.checked, "chains": ready.notReady,
+			})
+		default:
+			writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "checked-at": ready.checked})
+		}
+	})
+
+	logger.Info("serving health checks", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error("health check server stopped", "addr", addr, "err", err)
+	}
+}
@@ -42,6 +42,8 @@ func init() {
 	liteCmd.AddCommand(initLiteCmd())
 	liteCmd.AddCommand(updateLiteCmd())
 	liteCmd.AddCommand(deleteLiteCmd())
+	liteCmd.AddCommand(pruneLiteCmd())
+	liteCmd.AddCommand(unhaltLiteCmd())
 }
 
 func initLiteCmd() *cobra.Command {
@@ -59,12 +61,6 @@ func initLiteCmd() *cobra.Command {
 				return err
 			}
 
-			db, df, err := chain.NewLiteDB()
-			if err != nil {
-				return err
-			}
-			defer df()
-
 			url := viper.GetString(flagURL)
 			force := viper.GetBool(flagForce)
 			height, err := cmd.Flags().GetInt64(flags.FlagHeight)
@@ -78,12 +74,12 @@ func initLiteCmd() *cobra.Command {
 
 			switch {
 			case force: // force initialization from trusted node
-				_, err = chain.TrustNodeInitClient(db)
+				_, err = chain.TrustNodeInitClient()
 				if err != nil {
 					return err
 				}
 			case height > 0 && len(hash) > 0: // height and hash are given
-				_, err = chain.InitLiteClient(db, chain.TrustOptions(height, hash))
+				_, err = chain.InitLiteClient(chain.TrustOptions(height, hash))
 				if err != nil {
 					return wrapInitFailed(err)
 				}
@@ -98,7 +94,7 @@ func initLiteCmd() *cobra.Command {
 					return err
 				}
 
-				_, err = chain.InitLiteClient(db, to)
+				_, err = chain.InitLiteClient(to)
 				if err != nil {
 					return wrapInitFailed(err)
 				}
@@ -143,13 +139,7 @@ func updateLiteCmd() *cobra.Command {
 
 			switch {
 			case height > 0 && len(hash) > 0: // height and hash are given
-				db, df, err := chain.NewLiteDB()
-				if err != nil {
-					return err
-				}
-				defer df()
-
-				_, err = chain.InitLiteClient(db, chain.TrustOptions(height, hash))
+				_, err = chain.InitLiteClient(chain.TrustOptions(height, hash))
 				if err != nil {
 					return wrapInitFailed(err)
 				}
@@ -164,13 +154,7 @@ func updateLiteCmd() *cobra.Command {
 					return err
 				}
 
-				db, df, err := chain.NewLiteDB()
-				if err != nil {
-					return err
-				}
-				defer df()
-
-				_, err = chain.InitLiteClient(db, to)
+				_, err = chain.InitLiteClient(to)
 				if err != nil {
 					return wrapInitFailed(err)
 				}
@@ -277,6 +261,76 @@ func deleteLiteCmd() *cobra.Command {
 	return cmd
 }
 
+func unhaltLiteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unhalt [chain-id]",
+		Short: "resume relaying on a chain halted after its lite client saw conflicting headers",
+		Long: `Clear the halt of a chain whose lite client saw conflicting headers from its
+RPC endpoint and a witness. Only do so once the conflict, written to the evidence
+directory of the lite folder, has been investigated and the faulty node removed
+from the config. A running start keeps the chain halted until it is restarted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chain, err := config.c.GetChain(args[0])
+			if err != nil {
+				return err
+			}
+
+			if chain.Halted() == nil {
+				fmt.Printf("%s is not halted\n", chain.ChainID)
+				return nil
+			}
+
+			if err = chain.ClearHalt(); err != nil {
+				return err
+			}
+
+			fmt.Printf("cleared the halt of %s\n", chain.ChainID)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func pruneLiteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune [chain-id]",
+		Short: "remove all but the most recent headers from the lite client database",
+		Long: `Remove all but the most recent headers and validator sets from the lite client
+database. The number of headers kept defaults to global.lite-cache-size and can be
+overridden with --keep/-k.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chain, err := config.c.GetChain(args[0])
+			if err != nil {
+				return err
+			}
+
+			keep, err := cmd.Flags().GetInt(flagKeep)
+			if err != nil {
+				return err
+			}
+			if keep <= 0 {
+				keep = chain.LiteCacheSize
+			}
+			if keep <= 0 {
+				return errNoLiteCacheSize
+			}
+
+			pruned, err := chain.PruneLiteDB(keep)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("pruned %d headers from the lite client database for %s\n", pruned, chain.ChainID)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntP(flagKeep, "k", 0, "number of most recent headers to keep, defaults to global.lite-cache-size")
+	return cmd
+}
+
 func queryTrustOptions(url string) (out lite.TrustOptions, err error) {
 	// fetch from URL
 	res, err := http.Get(url)
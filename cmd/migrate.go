@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// currentConfigVersion is the config layout this build of the relayer reads
+var currentConfigVersion = len(configMigrations)
+
+// configMigration upgrades a raw config document by exactly one version
+type configMigration func(raw map[interface{}]interface{}) error
+
+// configMigrations is the registry of config upgrades. The migration at index i
+// takes a config of version i to version i+1. To change the config layout append
+// a new migration here, never edit one that has already been released.
+var configMigrations = []configMigration{
+	migrateConfigV0ToV1,
+	migrateConfigV1ToV2,
+}
+
+// defaultTrustingPeriod is used for chains whose config predates trusting-period
+const defaultTrustingPeriod = "336h"
+
+// migrateConfig takes the bytes of a config file of any known version and returns
+// the bytes of the same config upgraded to currentConfigVersion along with the
+// version the file was at before the upgrade
+func migrateConfig(bz []byte) ([]byte, int, error) {
+	raw := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(bz, &raw); err != nil {
+		return nil, 0, err
+	}
+
+	from, err := configVersion(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case from > currentConfigVersion:
+		return nil, from, fmt.Errorf("config version %d is newer than the latest supported version %d, upgrade the relayer", from, currentConfigVersion)
+	case from == currentConfigVersion:
+		return bz, from, nil
+	}
+
+	for v := from; v < currentConfigVersion; v++ {
+		if err = configMigrations[v](raw); err != nil {
+			return nil, from, fmt.Errorf("failed to migrate config from version %d to %d: %w", v, v+1, err)
+		}
+		raw["version"] = v + 1
+	}
+
+	// round trip through Config to drop unknown fields and normalize the layout
+	out, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, from, err
+	}
+
+	cfg := &Config{}
+	if err = yaml.Unmarshal(out, cfg); err != nil {
+		return nil, from, err
+	}
+
+	out, err = yaml.Marshal(cfg)
+	if err != nil {
+		return nil, from, err
+	}
+
+	return out, from, nil
+}
+
+// configVersion returns the version of a raw config, configs without one are version 0
+func configVersion(raw map[interface{}]interface{}) (int, error) {
+	v, ok := raw["version"]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	version, ok := v.(int)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("invalid config version %v", v)
+	}
+	return version, nil
+}
+
+// migrateConfigV0ToV1 upgrades the original config layout:
+// - per chain `counterparties` become `paths`, one for each pair of chains
+// - per chain `trust-options` are dropped, their period becomes `trusting-period`
+// - the misspelled `gas-adjustement` becomes `gas-adjustment`
+// - chains without a `trusting-period` get the default one
+func migrateConfigV0ToV1(raw map[interface{}]interface{}) error {
+	chains, err := rawList(raw, "chains")
+	if err != nil {
+		return err
+	}
+
+	paths, err := rawList(raw, "paths")
+	if err != nil {
+		return err
+	}
+
+	// migrated are the paths made from counterparties, one half-filled path is
+	// completed by the counterparty entry of its dst chain
+	var migrated []map[interface{}]interface{}
+	for i, c := range chains {
+		chain, ok := c.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("chains[%d] is not a map", i)
+		}
+
+		if adj, ok := chain["gas-adjustement"]; ok {
+			if _, set := chain["gas-adjustment"]; !set {
+				chain["gas-adjustment"] = adj
+			}
+			delete(chain, "gas-adjustement")
+		}
+
+		if to, ok := chain["trust-options"].(map[interface{}]interface{}); ok {
+			if period, ok := to["period"]; ok && chain["trusting-period"] == nil {
+				chain["trusting-period"] = fmt.Sprint(period)
+			}
+		}
+		delete(chain, "trust-options")
+
+		if chain["trusting-period"] == nil {
+			chain["trusting-period"] = defaultTrustingPeriod
+		}
+
+		cps, err := rawList(chain, "counterparties")
+		if err != nil {
+			return fmt.Errorf("chains[%d]: %w", i, err)
+		}
+		for j, cp := range cps {
+			counterparty, ok := cp.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("chains[%d].counterparties[%d] is not a map", i, j)
+			}
+			if p := counterpartyPath(migrated, counterparty["chain-id"], chain["chain-id"]); p != nil {
+				p["dst"].(map[interface{}]interface{})["client-id"] = counterparty["client-id"]
+				continue
+			}
+			migrated = append(migrated, map[interface{}]interface{}{
+				"src": map[interface{}]interface{}{
+					"chain-id":  chain["chain-id"],
+					"client-id": counterparty["client-id"],
+				},
+				"dst": map[interface{}]interface{}{
+					"chain-id": counterparty["chain-id"],
+				},
+			})
+		}
+		delete(chain, "counterparties")
+	}
+
+	for _, p := range migrated {
+		paths = append(paths, p)
+	}
+	raw["paths"] = paths
+	return nil
+}
+
+// counterpartyPath returns the path from src to dst whose dst client is still
+// unknown, nil if there is none
+func counterpartyPath(paths []map[interface{}]interface{}, src, dst interface{}) map[interface{}]interface{} {
+	for _, p := range paths {
+		s, d := p["src"].(map[interface{}]interface{}), p["dst"].(map[interface{}]interface{})
+		if _, set := d["client-id"]; !set && s["chain-id"] == src && d["chain-id"] == dst {
+			return p
+		}
+	}
+	return nil
+}
+
+// migrateConfigV1ToV2 renames `global.timeout`, which has always been the period
+// of the relay loop, to `global.relay-interval`
+func migrateConfigV1ToV2(raw map[interface{}]interface{}) error {
+	global, ok := raw["global"].(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+
+	if timeout, ok := global["timeout"]; ok {
+		if _, set := global["relay-interval"]; !set {
+			global["relay-interval"] = timeout
+		}
+		delete(global, "timeout")
+	}
+
+	return nil
+}
+
+// rawList returns the list stored under key, or an empty list if the key is unset
+func rawList(raw map[interface{}]interface{}, key string) ([]interface{}, error) {
+	v, ok := raw[key]
+	if !ok || v == nil {
+		return []interface{}{}, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list", key)
+	}
+	return list, nil
+}
+
+func configMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the config file in --home to the latest version, keeping a backup of the original",
+		Args:  cobra.NoArgs,
+		// the config may be too old to set up its chains, it's only read here
+		PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, err := cmd.Flags().GetString(flags.FlagHome)
+			if err != nil {
+				return err
+			}
+
+			cfgPath := path.Join(home, "config", "config.yaml")
+			bz, err := ioutil.ReadFile(cfgPath)
+			if err != nil {
+				return err
+			}
+
+			out, from, err := migrateConfig(bz)
+			if err != nil {
+				return err
+			}
+
+			if from == currentConfigVersion {
+				fmt.Printf("config at %s is already at version %d\n", cfgPath, currentConfigVersion)
+				return nil
+			}
+
+			info, err := os.Stat(cfgPath)
+			if err != nil {
+				return err
+			}
+
+			backup := fmt.Sprintf("%s.v%d.bak", cfgPath, from)
+			if err = ioutil.WriteFile(backup, bz, info.Mode()); err != nil {
+				return fmt.Errorf("failed to back up config: %w", err)
+			}
+
+			if err = ioutil.WriteFile(cfgPath, out, info.Mode()); err != nil {
+				return err
+			}
+
+			fmt.Printf("migrated %s from version %d to %d, backup written to %s\n", cfgPath, from, currentConfigVersion, backup)
+			return nil
+		},
+	}
+
+	return cmd
+}
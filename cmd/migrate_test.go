@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cosmos/relayer/relayer"
+	"gopkg.in/yaml.v2"
+)
+
+const configV0 = `global:
+  strategy: naive
+  timeout: 10s
+chains:
+- key: testkey
+  chain-id: ibc0
+  rpc-addr: http://localhost:26657
+  gas-adjustement: 1.5
+  trust-options:
+    period: 24h
+  counterparties:
+  - chain-id: ibc1
+    client-id: ibconeclient
+- key: testkey
+  chain-id: ibc1
+  rpc-addr: http://localhost:26557
+  counterparties:
+  - chain-id: ibc0
+    client-id: ibczeroclient
+  - chain-id: ibc2
+    client-id: ibctwoclient
+`
+
+func TestMigrateConfig(t *testing.T) {
+	current, err := yaml.Marshal(&Config{Version: currentConfigVersion, Global: GlobalConfig{Strategy: "naive"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		config   string
+		wantFrom int
+		wantErr  bool
+		want     func(t *testing.T, out []byte)
+	}{
+		{
+			name:     "v0 to latest",
+			config:   configV0,
+			wantFrom: 0,
+			want: func(t *testing.T, out []byte) {
+				cfg := &Config{}
+				if err := yaml.Unmarshal(out, cfg); err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Version != currentConfigVersion {
+					t.Errorf("version = %d, want %d", cfg.Version, currentConfigVersion)
+				}
+				if cfg.Global.RelayInterval != "10s" {
+					t.Errorf("relay-interval = %q, want the v0 timeout", cfg.Global.RelayInterval)
+				}
+				if c := cfg.Chains[0]; c.GasAdjustment != 1.5 || c.TrustingPeriod != "24h" {
+					t.Errorf("chains[0] gas-adjustment %v, trusting-period %q, want 1.5 and 24h", c.GasAdjustment, c.TrustingPeriod)
+				}
+				if c := cfg.Chains[1]; c.TrustingPeriod != defaultTrustingPeriod {
+					t.Errorf("chains[1] trusting-period = %q, want %q", c.TrustingPeriod, defaultTrustingPeriod)
+				}
+				want := []relayer.Path{
+					{
+						Src: relayer.PathEnd{ChainID: "ibc0", ClientID: "ibconeclient"},
+						Dst: relayer.PathEnd{ChainID: "ibc1", ClientID: "ibczeroclient"},
+					},
+					{
+						Src: relayer.PathEnd{ChainID: "ibc1", ClientID: "ibctwoclient"},
+						Dst: relayer.PathEnd{ChainID: "ibc2"},
+					},
+				}
+				if !reflect.DeepEqual(cfg.Paths, want) {
+					t.Errorf("paths = %+v, want %+v", cfg.Paths, want)
+				}
+			},
+		},
+		{
+			name:     "v1 to latest",
+			config:   "version: 1\nglobal:\n  strategy: naive\n  timeout: 5s\n",
+			wantFrom: 1,
+			want: func(t *testing.T, out []byte) {
+				cfg := &Config{}
+				if err := yaml.Unmarshal(out, cfg); err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Version != currentConfigVersion || cfg.Global.RelayInterval != "5s" {
+					t.Errorf("version %d, relay-interval %q, want %d and 5s", cfg.Version, cfg.Global.RelayInterval, currentConfigVersion)
+				}
+			},
+		},
+		{
+			name:     "already current",
+			config:   string(current),
+			wantFrom: currentConfigVersion,
+			want: func(t *testing.T, out []byte) {
+				if string(out) != string(current) {
+					t.Errorf("current config changed to %s", out)
+				}
+			},
+		},
+		{
+			name:     "unknown version",
+			config:   "version: 99\n",
+			wantFrom: 99,
+			wantErr:  true,
+		},
+		{
+			name:    "invalid version",
+			config:  "version: -1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		out, from, err := migrateConfig([]byte(tc.config))
+		switch {
+		case tc.wantErr && err == nil:
+			t.Errorf("%s: expected an error", tc.name)
+			continue
+		case !tc.wantErr && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if from != tc.wantFrom {
+			t.Errorf("%s: from = %d, want %d", tc.name, from, tc.wantFrom)
+		}
+		if tc.want != nil {
+			tc.want(t, out)
+		}
+	}
+}
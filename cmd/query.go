@@ -22,6 +22,10 @@ func init() {
 	queryCmd.AddCommand(queryConnection())
 	queryCmd.AddCommand(queryConnectionsUsingClient())
 	queryCmd.AddCommand(queryChannel())
+	queryCmd.AddCommand(queryUnrelayed())
+	queryCmd.AddCommand(queryPacketCommitment())
+	queryCmd.AddCommand(queryPacketAck())
+	queryCmd.AddCommand(queryNextSeqRecv())
 }
 
 // queryCmd represents the chain command
@@ -302,3 +306,137 @@ func queryChannel() *cobra.Command {
 
 	return outputFlags(paginationFlags(cmd))
 }
+
+func queryUnrelayed() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unrelayed [path]",
+		Short: "Query the packets and acknowledgements waiting to be relayed in both directions of the path with the given index",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst, err := config.pathChains(args[0])
+			if err != nil {
+				return err
+			}
+
+			srcToDst, err := relayer.QueryUnrelayed(src, dst)
+			if err != nil {
+				return err
+			}
+
+			dstToSrc, err := relayer.QueryUnrelayed(dst, src)
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput([]*relayer.UnrelayedSequences{srcToDst, dstToSrc}, cmd)
+		},
+	}
+
+	return outputFlags(cmd)
+}
+
+func queryPacketCommitment() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "packet-commitment [chain-id] [channel-id] [port-id] [sequence]",
+		Short: "Query the commitment of a packet sent on a channel, with its proof",
+		Args:  cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chain, err := config.c.GetChain(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err = chain.SetNewFullPath("", "", args[1], args[2]); err != nil {
+				return err
+			}
+
+			seq, err := strconv.ParseUint(args[3], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			height, err := chain.QueryLatestHeight()
+			if err != nil {
+				return err
+			}
+
+			res, err := chain.QueryPacketCommitment(height, seq)
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput(res, cmd)
+		},
+	}
+
+	return outputFlags(cmd)
+}
+
+func queryPacketAck() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "packet-ack [chain-id] [channel-id] [port-id] [sequence]",
+		Short: "Query the acknowledgement written on a channel for a received packet, with its proof",
+		Args:  cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chain, err := config.c.GetChain(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err = chain.SetNewFullPath("", "", args[1], args[2]); err != nil {
+				return err
+			}
+
+			seq, err := strconv.ParseUint(args[3], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			height, err := chain.QueryLatestHeight()
+			if err != nil {
+				return err
+			}
+
+			res, err := chain.QueryPacketAcknowledgement(height, seq)
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput(res, cmd)
+		},
+	}
+
+	return outputFlags(cmd)
+}
+
+func queryNextSeqRecv() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "next-seq-recv [chain-id] [channel-id] [port-id]",
+		Short: "Query the sequence of the next packet a channel expects to receive, with its proof",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chain, err := config.c.GetChain(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err = chain.SetNewFullPath("", "", args[1], args[2]); err != nil {
+				return err
+			}
+
+			height, err := chain.QueryLatestHeight()
+			if err != nil {
+				return err
+			}
+
+			res, err := chain.QueryNextSequenceRecv(height)
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput(res, cmd)
+		},
+	}
+
+	return outputFlags(cmd)
+}
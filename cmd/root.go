@@ -26,9 +26,12 @@ import (
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/auth"
 	authvesting "github.com/cosmos/cosmos-sdk/x/auth/vesting"
+	"github.com/cosmos/cosmos-sdk/x/evidence"
 	"github.com/cosmos/cosmos-sdk/x/ibc"
+	"github.com/cosmos/relayer/relayer"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/log"
 	"gopkg.in/yaml.v2"
 )
 
@@ -38,6 +41,10 @@ var (
 	config      *Config
 	defaultHome = os.ExpandEnv("$HOME/.relayer")
 	cdc         *codec.Codec
+
+	// logger writes to stderr until it is configured by the log settings in the
+	// config, see setChains
+	logger = log.NewTMLogger(log.NewSyncWriter(os.Stderr))
 )
 
 func init() {
@@ -67,6 +74,7 @@ func init() {
 	authvesting.RegisterCodec(cdc)
 	auth.RegisterCodec(cdc)
 	keys.RegisterCodec(cdc)
+	evidence.AppModuleBasic{}.RegisterCodec(cdc)
 	ibc.AppModuleBasic{}.RegisterCodec(cdc)
 	cdc.Seal()
 
@@ -93,6 +101,8 @@ func configCmd() *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(configMigrateCmd())
+
 	return cmd
 }
 
@@ -116,26 +126,81 @@ func chainsCmd() *cobra.Command {
 func pathsCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "paths",
-		Short: "print out configured paths with direction",
+		Short: "print out configured paths with direction, prefixed by their index",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			for _, p := range config.Paths {
-				fmt.Println(p.String())
+			for i, p := range config.Paths {
+				fmt.Printf("%d: %s\n", i, p.String())
 			}
 			return nil
 		},
 	}
+
+	cmd.AddCommand(pathStateCmd())
+
 	return cmd
 }
 
+func pathStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state [path]",
+		Short: "Show the relay progress recorded for the path with the given index, or reset it with --reset",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := config.path(args[0])
+			if err != nil {
+				return err
+			}
+
+			progress, err := relayer.OpenProgressStore(homePath)
+			if err != nil {
+				return err
+			}
+			defer progress.Close()
+
+			reset, err := cmd.Flags().GetBool(flagReset)
+			if err != nil {
+				return err
+			}
+
+			if reset {
+				if err = progress.Reset(p); err != nil {
+					return err
+				}
+				fmt.Printf("reset relay progress of %s\n", p.String())
+				return nil
+			}
+
+			state, err := progress.Progress(p)
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput(state, cmd)
+		},
+	}
+
+	cmd.Flags().Bool(flagReset, false, "forget the recorded progress so the path is scanned from the start")
+	return outputFlags(cmd)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	rootCmd.PersistentPreRunE = func(_ *cobra.Command, _ []string) error {
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
 		// reads `homeDir/config/config.yaml` into `var config *Config` before each command
-		return initConfig(rootCmd)
+		return initConfig(rootCmd, cmd == startCmd)
+	}
+
+	err := rootCmd.Execute()
+
+	// release the lite client databases held by the configured chains
+	if config != nil {
+		if cerr := config.c.Close(); cerr != nil {
+			fmt.Println(cerr)
+		}
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -16,7 +16,12 @@ limitations under the License.
 package cmd
 
 import (
-	"fmt"
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/cosmos/relayer/relayer"
@@ -29,30 +34,189 @@ var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "starts the relayer using the configured chains and strategy",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		d, err := time.ParseDuration(config.Global.Timeout)
+		d, err := config.Global.relayInterval()
 		if err != nil {
 			return err
 		}
 
+		hc, err := config.Global.rpcHealthCheckInterval()
+		if err != nil {
+			return err
+		}
+
+		ei, err := config.Global.clientExpiryCheckInterval()
+		if err != nil {
+			return err
+		}
+
+		threshold, err := config.Global.clientUpdateThreshold()
+		if err != nil {
+			return err
+		}
+
+		bi, err := config.Global.balanceCheckInterval()
+		if err != nil {
+			return err
+		}
+
+		st, err := config.Global.relayStallThreshold(d)
+		if err != nil {
+			return err
+		}
+
+		et, err := config.Global.eventStaleTimeout()
+		if err != nil {
+			return err
+		}
+
+		apiAddr, err := config.Global.apiAddr()
+		if err != nil {
+			return err
+		}
+
+		// Notifications are only sent by start, the queued ones are delivered on return
+		notifier, err := config.Global.Notifications.newNotifier(logger)
+		if err != nil {
+			return err
+		}
+		defer notifier.Close()
 		for _, chain := range config.c {
-			// NOTE: this is now hardcoded to a once every 5 seconds update,
-			// this should be made configurable
-			go chain.StartUpdatingLiteClient(time.Duration(5 * time.Second))
+			chain.SetNotifier(notifier)
+		}
+
+		// Resume relaying from the progress recorded by previous runs
+		progress, err := relayer.OpenProgressStore(homePath)
+		if err != nil {
+			return err
+		}
+		defer progress.Close()
+
+		state := newDaemon(progress)
+		go state.serveHealth(orDefault(config.Global.HealthAddr, defaultHealthAddr), st)
+		if config.Global.MetricsAddr != "" {
+			go serveMetrics(config.Global.MetricsAddr)
+		}
+		if apiAddr != "" {
+			go state.serveAPI(apiAddr, config.Global.APIToken)
+		}
+
+		for _, chain := range config.c {
+			go chain.Client.StartHealthChecks(hc)
+			go chain.StartUpdatingLiteClient(chain.LiteUpdateInterval)
+			go chain.StartBalanceChecks(bi)
 
 			// TODO: Figure out how/when to stop
 		}
 
-		// The relayer will continuously run the strategy declared in the config file
+		// In event driven mode relay on a chain's paths as soon as it has IBC events
+		events := make(chan relayer.RelayEvent, len(config.c))
+		if config.Global.EventDriven {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			for _, chain := range config.c {
+				go chain.SubscribeRelayEvents(ctx, events, et)
+			}
+		}
+
+		// Stop relaying on interrupt, Execute closes the chains on return
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+		// The relayer will continuously run the strategy declared in the config file,
+		// polling every relay interval even when event driven
 		ticker := time.NewTicker(d)
-		for ; true; <-ticker.C {
-			err = relayer.Relay(config.Global.Strategy, config.c, config.Paths)
+		defer ticker.Stop()
+		paths := relayer.Paths(config.Paths)
+		var expiryChecked time.Time
+		checker := relayer.NewMisbehaviourChecker(progress)
+		for {
+			// Keep the clients of quiet paths from expiring, this shares the chains'
+			// path ends with Relay so it runs in the same loop
+			if time.Since(expiryChecked) >= ei {
+				if err = relayer.UpdateExpiringClients(config.c, config.Paths, threshold); err != nil {
+					logger.Error("failed to check clients for expiry", "err", err)
+					state.recordErr(err)
+				}
+				expiryChecked = time.Now()
+			}
+
+			// Only the consensus states stored since the last round are checked
+			if config.Global.CheckMisbehaviour {
+				if _, err = checker.Check(config.c, config.Paths); err != nil {
+					logger.Error("failed to check clients for misbehaviour", "err", err)
+					state.recordErr(err)
+				}
+			}
+
+			// Paths paused through the API are skipped
+			err = relayer.Relay(config.Global.Strategy, config.c, state.unpaused(paths), progress)
 			if err != nil {
 				// TODO: This should have a better error handling strategy
 				// Ideally some errors are just logged while others halt the process
-				fmt.Println(err)
+				logger.Error("relay failed", "err", err)
+				state.recordErr(err)
 			}
-		}
+			state.relayed()
+			state.checkReady(config.c)
 
-		return nil
+			// API requests using the chains run here between relay rounds
+		wait:
+			for {
+				select {
+				case <-ticker.C:
+					paths = config.Paths
+					break wait
+				case <-state.trigger:
+					paths = config.Paths
+					break wait
+				case ev := <-events:
+					paths = pathsWithEvents(ev, events)
+					break wait
+				case req := <-state.requests:
+					req()
+				case <-sigCh:
+					return nil
+				}
+			}
+		}
 	},
 }
+
+// serveMetrics serves the relayer's metrics at /metrics on addr
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", relayer.MetricsHandler())
+	logger.Info("serving metrics", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error("metrics server stopped", "addr", addr, "err", err)
+	}
+}
+
+// pathsWithEvents returns the configured paths on the chain of ev and of any
+// other events already queued, so a burst of events triggers a single relay
+func pathsWithEvents(ev relayer.RelayEvent, events <-chan relayer.RelayEvent) relayer.Paths {
+	logRelayEvent(ev)
+	chains := map[string]bool{ev.ChainID: true}
+	for drained := false; !drained; {
+		select {
+		case ev = <-events:
+			logRelayEvent(ev)
+			chains[ev.ChainID] = true
+		default:
+			drained = true
+		}
+	}
+
+	var out relayer.Paths
+	for _, path := range config.Paths {
+		if chains[path.Src.ChainID] || chains[path.Dst.ChainID] {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+// logRelayEvent logs an event that triggers a relay round
+func logRelayEvent(ev relayer.RelayEvent) {
+	logger.Info("relay event", "chain-id", ev.ChainID, "height", ev.Height, "types", strings.Join(ev.Types, ","), "missed", ev.Missed)
+}
@@ -16,7 +16,7 @@ limitations under the License.
 package cmd
 
 import (
-	"time"
+	"strconv"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	chanState "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/exported"
@@ -32,6 +32,11 @@ func init() {
 	transactionCmd.AddCommand(createChannelCmd())
 	transactionCmd.AddCommand(createChannelStepCmd())
 	transactionCmd.AddCommand(updateClientCmd())
+	transactionCmd.AddCommand(relayPacketCmd())
+	transactionCmd.AddCommand(relayAckCmd())
+	transactionCmd.AddCommand(timeoutPacketCmd())
+	transactionCmd.AddCommand(transferCmd())
+	transactionCmd.AddCommand(misbehaviourCmd())
 	transactionCmd.AddCommand(rawTransactionCmd)
 	rawTransactionCmd.AddCommand(connTry())
 	rawTransactionCmd.AddCommand(connAck())
@@ -174,7 +179,11 @@ func createConnectionCmd() *cobra.Command {
 		Long:  "FYI: DRAGONS HERE, not tested",
 		Args:  cobra.ExactArgs(6),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			timeout := 5 * time.Second
+			interval, err := config.Global.relayInterval()
+			if err != nil {
+				return err
+			}
+
 			src, dst := args[0], args[1]
 			chains, err := config.c.GetChains(src, dst)
 			if err != nil {
@@ -183,7 +192,7 @@ func createConnectionCmd() *cobra.Command {
 
 			// TODO: validate identifiers ICS24
 
-			err = chains[src].CreateConnection(chains[dst], args[2], args[3], args[4], args[5], timeout)
+			err = chains[src].CreateConnection(chains[dst], args[2], args[3], args[4], args[5], interval)
 			if err != nil {
 				return err
 			}
@@ -221,7 +230,7 @@ func createConnectionStepCmd() *cobra.Command {
 				return err
 			}
 
-			var res sdk.TxResponse
+			var res []sdk.TxResponse
 			if len(msgs.Src) > 0 {
 				res, err = chains[src].SendMsgs(msgs.Src)
 				if err != nil {
@@ -248,7 +257,11 @@ func createChannelCmd() *cobra.Command {
 		Long:  "FYI: DRAGONS HERE, not tested",
 		Args:  cobra.ExactArgs(11),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			timeout := 5 * time.Second
+			interval, err := config.Global.relayInterval()
+			if err != nil {
+				return err
+			}
+
 			src, dst := args[0], args[1]
 			srcClientID, dstClientID := args[2], args[3]
 			srcConnID, dstConnID := args[4], args[5]
@@ -262,7 +275,7 @@ func createChannelCmd() *cobra.Command {
 
 			// TODO: validate identifiers ICS24
 
-			err = chains[src].CreateChannel(chains[dst], srcClientID, dstClientID, srcConnID, dstConnID, srcChanID, dstChanID, srcPortID, dstPortID, timeout, ordering)
+			err = chains[src].CreateChannel(chains[dst], srcClientID, dstClientID, srcConnID, dstConnID, srcChanID, dstChanID, srcPortID, dstPortID, interval, ordering)
 			if err != nil {
 				return err
 			}
@@ -301,7 +314,7 @@ func createChannelStepCmd() *cobra.Command {
 				return err
 			}
 
-			var res sdk.TxResponse
+			var res []sdk.TxResponse
 			if len(msgs.Src) > 0 {
 				res, err = chains[src].SendMsgs(msgs.Src)
 				if err != nil {
@@ -321,6 +334,162 @@ func createChannelStepCmd() *cobra.Command {
 	return outputFlags(cmd)
 }
 
+func relayPacketCmd() *cobra.Command {
+	return packetCmd("relay-packet [path] [sequence]",
+		"deliver the packet with the given sequence sent on the src chain of the path to its dst chain",
+		relayer.RelayPacketMsgs)
+}
+
+func relayAckCmd() *cobra.Command {
+	return packetCmd("relay-ack [path] [sequence]",
+		"deliver the acknowledgement the dst chain of the path wrote for the packet with the given sequence back to its src chain",
+		relayer.RelayAckMsgs)
+}
+
+func timeoutPacketCmd() *cobra.Command {
+	return packetCmd("timeout-packet [path] [sequence]",
+		"time out the packet with the given sequence sent on the src chain of the path once its dst chain is past the timeout height",
+		relayer.TimeoutPacketMsgs)
+}
+
+func transferCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transfer [src-chain-id] [dst-chain-id] [amount] [receiver]",
+		Short: "send an ICS20 transfer of amount from the src-chain key to receiver on dst-chain over the first configured path between them",
+		Args:  cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst, err := config.pathBetween(args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			amount, err := sdk.ParseCoins(args[2])
+			if err != nil {
+				return err
+			}
+
+			receiver, err := sdk.GetFromBech32(args[3], dst.AccountPrefix)
+			if err != nil {
+				return err
+			}
+
+			msg := src.Transfer(amount, receiver)
+			res, err := src.SendMsg(msg)
+			if err != nil {
+				return err
+			}
+
+			if err = PrintOutput(res, cmd); err != nil {
+				return err
+			}
+
+			relay, err := cmd.Flags().GetBool(flagRelay)
+			if err != nil || !relay || res.Code != 0 {
+				return err
+			}
+
+			seq, err := src.QueryTransferSequence(dst, msg, res.Height)
+			if err != nil {
+				return err
+			}
+
+			msgs, err := relayer.RelayPacketMsgs(src, dst, seq)
+			if err != nil {
+				return err
+			}
+
+			relayRes, err := dst.SendMsgs(msgs.Dst)
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput(relayRes, cmd)
+		},
+	}
+
+	cmd.Flags().Bool(flagRelay, false, "wait for the transfer to be committed and relay its packet to dst-chain")
+	return outputFlags(cmd)
+}
+
+func misbehaviourCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "misbehaviour [path]",
+		Short: "check the clients on both ends of the path against the lite clients and submit evidence of any conflict",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := config.path(args[0])
+			if err != nil {
+				return err
+			}
+
+			found, err := relayer.NewMisbehaviourChecker(nil).Check(config.c, []relayer.Path{p})
+			if err != nil {
+				return err
+			}
+
+			return PrintOutput(found, cmd)
+		},
+	}
+	return outputFlags(cmd)
+}
+
+// packetCmd returns a command that relays the datagrams built by msgs for a single
+// packet on the path with the given index, updating the receiving client first
+func packetCmd(use, short string, msgs func(src, dst *relayer.Chain, seq uint64) (*relayer.RelayMsgs, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst, err := config.pathChains(args[0])
+			if err != nil {
+				return err
+			}
+
+			reverse, err := cmd.Flags().GetBool(flagReverse)
+			if err != nil {
+				return err
+			}
+			if reverse {
+				src, dst = dst, src
+			}
+
+			seq, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+
+			out, err := msgs(src, dst, seq)
+			if err != nil {
+				return err
+			}
+
+			for _, c := range []struct {
+				chain *relayer.Chain
+				msgs  []sdk.Msg
+			}{{src, out.Src}, {dst, out.Dst}} {
+				if len(c.msgs) == 0 {
+					continue
+				}
+
+				res, err := c.chain.SendMsgs(c.msgs)
+				if err != nil {
+					return err
+				}
+
+				if err = PrintOutput(res, cmd); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP(flagReverse, "r", false, "relay in the dst -> src direction of the path")
+	return outputFlags(cmd)
+}
+
 ////////////////////////////////////////
 ////  RAW IBC TRANSACTION COMMANDS  ////
 ////////////////////////////////////////
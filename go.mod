@@ -5,6 +5,8 @@ go 1.13
 require (
 	github.com/cosmos/cosmos-sdk v0.34.4-0.20200214060456-38d87b4a1e87
 	github.com/cosmos/go-bip39 v0.0.0-20180819234021-555e2067c45d
+	github.com/go-kit/kit v0.9.0
+	github.com/prometheus/client_golang v0.9.3
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.6.2
 	github.com/tendermint/tendermint v0.33.0
@@ -0,0 +1,128 @@
+package relayer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/bank"
+)
+
+// ErrInsufficientBalance is returned instead of sending txs to a chain while the
+// relayer's account there can't pay for them
+var ErrInsufficientBalance = errors.New("insufficient balance to pay fees")
+
+// BalanceStatus is the balance of the relayer's account on a chain as of its last check
+type BalanceStatus struct {
+	Balance    sdk.Coins `json:"balance" yaml:"balance"`
+	MinBalance sdk.Coins `json:"min-balance" yaml:"min-balance"`
+	CheckedAt  time.Time `json:"checked-at" yaml:"checked-at"`
+
+	// Low is set when the balance is below MinBalance
+	Low bool `json:"low" yaml:"low"`
+
+	// Paused is set when the balance can't pay the fee of a tx, no txs are sent
+	// to the chain until a check finds enough funds
+	Paused bool `json:"paused" yaml:"paused"`
+}
+
+// QueryBalance returns the balance of the relayer's account on the chain
+func (c *Chain) QueryBalance() (sdk.Coins, error) {
+	bz, err := c.Cdc.MarshalJSON(bank.NewQueryAllBalancesParams(c.MustGetAddress()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query params: %w", err)
+	}
+
+	res, _, err := c.QueryWithData(fmt.Sprintf("custom/%s/%s", bank.QuerierRoute, bank.QueryAllBalances), bz)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance sdk.Coins
+	if err = c.Cdc.UnmarshalJSON(res, &balance); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal balance: %w", err)
+	}
+	return balance, nil
+}
+
+// BalanceStatus returns the balance as of the last check
+func (c *Chain) BalanceStatus() BalanceStatus {
+	c.balanceMtx.Lock()
+	defer c.balanceMtx.Unlock()
+	return c.balance
+}
+
+// StartBalanceChecks begins a loop that periodically checks the balance of the
+// relayer's account on the chain
+func (c *Chain) StartBalanceChecks(period time.Duration) {
+	ticker := time.NewTicker(period)
+	for ; true; <-ticker.C {
+		if _, err := c.CheckBalance(); err != nil {
+			c.logger.Error("failed to check balance", "err", err)
+		}
+	}
+}
+
+// CheckBalance queries the balance of the relayer's account and warns if it is
+// below the chain's MinBalance. Sending txs is paused while the balance can't pay
+// the fee of a tx and resumed once it can.
+func (c *Chain) CheckBalance() (BalanceStatus, error) {
+	balance, err := c.QueryBalance()
+	if err != nil {
+		return c.BalanceStatus(), err
+	}
+
+	status := BalanceStatus{
+		Balance:    balance,
+		MinBalance: c.MinBalance,
+		CheckedAt:  time.Now(),
+		Low:        !c.MinBalance.Empty() && !balance.IsAllGTE(c.MinBalance),
+		Paused:     !balance.IsAllGTE(c.Fee(c.feeCheckGas(), 0)),
+	}
+
+	c.balanceMtx.Lock()
+	prev := c.balance
+	c.balance = status
+	c.balanceMtx.Unlock()
+	c.observeBalance(prev, status)
+
+	logger := c.logger.With("address", c.MustGetAddress(), "balance", balance)
+	switch {
+	case status.Paused:
+		logger.Error("balance too low to pay fees, not sending txs until it is funded")
+	case prev.Paused:
+		logger.Info("balance funded, sending txs again")
+	}
+	if status.Low && !status.Paused {
+		logger.Error("balance below the minimum", "min-balance", c.MinBalance)
+	}
+
+	return status, nil
+}
+
+// feeCheckGas is the gas of the tx whose fee the balance must cover to keep
+// sending txs, the chain's Gas or the default gas limit if it is simulated
+func (c *Chain) feeCheckGas() uint64 {
+	if c.Gas > 0 {
+		return c.Gas
+	}
+	return flags.DefaultGasLimit
+}
+
+// pause pauses sending txs to the chain until the next balance check finds enough funds
+func (c *Chain) pause() {
+	c.balanceMtx.Lock()
+	defer c.balanceMtx.Unlock()
+	c.balance.Paused = true
+	balancePaused.WithLabelValues(c.ChainID).Set(1)
+}
+
+// paused returns ErrInsufficientBalance while sending txs to the chain is paused
+func (c *Chain) paused() error {
+	if status := c.BalanceStatus(); status.Paused {
+		return fmt.Errorf("%w: %s on %s has %s", ErrInsufficientBalance, c.MustGetAddress(), c.ChainID, status.Balance)
+	}
+	return nil
+}
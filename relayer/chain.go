@@ -1,8 +1,11 @@
 package relayer
 
 import (
+	"encoding/hex"
 	"fmt"
 	"path"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/client/context"
@@ -10,42 +13,152 @@ import (
 	"github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/crypto/keys"
 	"github.com/cosmos/cosmos-sdk/x/auth"
+	authexported "github.com/cosmos/cosmos-sdk/x/auth/exported"
 	"github.com/tendermint/tendermint/libs/log"
+	lite "github.com/tendermint/tendermint/lite2"
+	litep "github.com/tendermint/tendermint/lite2/provider"
+	litehttp "github.com/tendermint/tendermint/lite2/provider/http"
+	dbm "github.com/tendermint/tm-db"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	libclient "github.com/tendermint/tendermint/rpc/lib/client"
 )
 
+// txPollPeriod is how often the chain is queried while waiting for a tx to commit
+var txPollPeriod = time.Second
+
+// ChainOptions configure a Chain, coins and durations are given as strings to be
+// parsed by NewChain
+type ChainOptions struct {
+	Key           string
+	ChainID       string
+	RPCAddr       string
+	AccountPrefix string
+	Gas           uint64
+	GasAdjustment float64
+	GasPrices     string
+	Fees          string
+	MaxFee        string
+	FeeBump       float64
+	MinBalance    string
+	DefaultDenom  string
+	Memo          string
+
+	// HomePath is the relayer home holding the keys and lite client databases
+	HomePath      string
+	LiteCacheSize int
+
+	TrustingPeriod        string
+	LiteUpdateInterval    string
+	RPCTimeout            string
+	TxConfirmationTimeout string
+	MaxBlockAge           string
+
+	MaxMsgsPerTx int
+	MaxTxBytes   int
+
+	BackupRPCAddrs []string
+	Witnesses      []string
+}
+
 // NewChain returns a new instance of Chain
 // NOTE: It does not by default create the verifier. This needs a working connection
 // and blocks running the app if NewChain does this by default.
-func NewChain(key, chainID, rpcAddr, accPrefix string, gas uint64, gasAdj float64,
-	gasPrices, defaultDenom, memo, homePath string, liteCacheSize int, trustingPeriod,
-	dir string, cdc *codec.Codec) (*Chain, error) {
-	keybase, err := keys.NewKeyring(chainID, "test", keysDir(homePath), nil)
+func NewChain(opts ChainOptions, cdc *codec.Codec, logger log.Logger) (*Chain, error) {
+	logger = logger.With("chain-id", opts.ChainID)
+
+	keybase, err := keys.NewKeyring(opts.ChainID, "test", keysDir(opts.HomePath), nil)
 	if err != nil {
 		return &Chain{}, err
 	}
 
-	client, err := rpcclient.NewHTTP(rpcAddr, "/websocket")
+	rt, err := time.ParseDuration(opts.RPCTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rpc timeout (%s) for chain %s", opts.RPCTimeout, opts.ChainID)
+	}
+
+	mba, err := time.ParseDuration(opts.MaxBlockAge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse max block age (%s) for chain %s", opts.MaxBlockAge, opts.ChainID)
+	}
+
+	client, err := NewRPCClient(opts.ChainID, append([]string{opts.RPCAddr}, opts.BackupRPCAddrs...), rt, mba, logger)
 	if err != nil {
 		return &Chain{}, err
 	}
 
-	gp, err := sdk.ParseDecCoins(gasPrices)
+	witnessProviders := make([]litep.Provider, len(opts.Witnesses))
+	for i, addr := range opts.Witnesses {
+		wc, err := newRPCClient(addr, rt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid witness %s for chain %s: %w", addr, opts.ChainID, err)
+		}
+		witnessProviders[i] = litehttp.NewWithClient(opts.ChainID, wc)
+	}
+
+	gp, err := sdk.ParseDecCoins(opts.GasPrices)
 	if err != nil {
 		return &Chain{}, err
 	}
 
-	tp, err := time.ParseDuration(trustingPeriod)
+	fs, err := sdk.ParseCoins(opts.Fees)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fees (%s) for chain %s: %w", opts.Fees, opts.ChainID, err)
+	}
+
+	mf, err := sdk.ParseCoins(opts.MaxFee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse max fee (%s) for chain %s: %w", opts.MaxFee, opts.ChainID, err)
+	}
+
+	if opts.FeeBump != 0 && opts.FeeBump < 1 {
+		return nil, fmt.Errorf("fee bump (%v) for chain %s must be at least 1, or 0 to never bump fees", opts.FeeBump, opts.ChainID)
+	}
+	fb, err := sdk.NewDecFromStr(strconv.FormatFloat(opts.FeeBump, 'f', -1, 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fee bump (%v) for chain %s: %w", opts.FeeBump, opts.ChainID, err)
+	}
+
+	mb, err := sdk.ParseCoins(opts.MinBalance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse min balance (%s) for chain %s: %w", opts.MinBalance, opts.ChainID, err)
+	}
+
+	tp, err := time.ParseDuration(opts.TrustingPeriod)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse duration (%s) for chain %s", trustingPeriod, chainID)
+		return nil, fmt.Errorf("failed to parse duration (%s) for chain %s", opts.TrustingPeriod, opts.ChainID)
+	}
+
+	lui, err := time.ParseDuration(opts.LiteUpdateInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lite update interval (%s) for chain %s", opts.LiteUpdateInterval, opts.ChainID)
+	}
+
+	tct, err := time.ParseDuration(opts.TxConfirmationTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tx confirmation timeout (%s) for chain %s", opts.TxConfirmationTimeout, opts.ChainID)
 	}
 
 	return &Chain{
-		Key: key, ChainID: chainID, RPCAddr: rpcAddr, AccountPrefix: accPrefix, Gas: gas,
-		GasAdjustment: gasAdj, GasPrices: gp, DefaultDenom: defaultDenom, Memo: memo, Keybase: keybase,
-		Client: client, Cdc: cdc, TrustingPeriod: tp, HomePath: homePath}, nil
+		Key: opts.Key, ChainID: opts.ChainID, RPCAddr: opts.RPCAddr, AccountPrefix: opts.AccountPrefix, Gas: opts.Gas,
+		GasAdjustment: opts.GasAdjustment, GasPrices: gp, Fees: fs, MaxFee: mf, FeeBump: opts.FeeBump, feeBump: fb, MinBalance: mb,
+		DefaultDenom: opts.DefaultDenom, Memo: opts.Memo, Keybase: keybase,
+		Client: client, Cdc: cdc, TrustingPeriod: tp, HomePath: opts.HomePath, LiteCacheSize: opts.LiteCacheSize,
+		LiteUpdateInterval: lui, RPCTimeout: rt, TxConfirmationTimeout: tct,
+		MaxMsgsPerTx: opts.MaxMsgsPerTx, MaxTxBytes: opts.MaxTxBytes,
+		Witnesses: opts.Witnesses, witnesses: witnessProviders, BackupRPCAddrs: opts.BackupRPCAddrs, logger: logger}, nil
+}
+
+// newRPCClient returns a tendermint RPC client whose requests time out after timeout
+func newRPCClient(addr string, timeout time.Duration) (*rpcclient.HTTP, error) {
+	httpClient, err := libclient.DefaultHTTPClient(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient.Timeout = timeout
+	return rpcclient.NewHTTPWithClient(addr, "/websocket", httpClient)
 }
 
 // Chain represents the necessary data for connecting to and indentifying a chain and its counterparites
@@ -57,23 +170,86 @@ type Chain struct {
 	Gas            uint64        `yaml:"gas,omitempty"`
 	GasAdjustment  float64       `yaml:"gas-adjustment,omitempty"`
 	GasPrices      sdk.DecCoins  `yaml:"gas-prices,omitempty"`
+	Fees           sdk.Coins     `yaml:"fees,omitempty"`
+	MaxFee         sdk.Coins     `yaml:"max-fee,omitempty"`
+	FeeBump        float64       `yaml:"fee-bump,omitempty"`
+	MinBalance     sdk.Coins     `yaml:"min-balance,omitempty"`
 	DefaultDenom   string        `yaml:"default-denom,omitempty"`
 	Memo           string        `yaml:"memo,omitempty"`
 	TrustingPeriod time.Duration `yaml:"trusting-period"`
+	LiteCacheSize  int           `yaml:"lite-cache-size"`
 	HomePath       string
 	PathEnd        *PathEnd
 
+	LiteUpdateInterval    time.Duration `yaml:"lite-update-interval"`
+	RPCTimeout            time.Duration `yaml:"rpc-timeout"`
+	TxConfirmationTimeout time.Duration `yaml:"tx-confirmation-timeout"`
+
+	// MaxMsgsPerTx and MaxTxBytes limit the number of msgs in a tx and their
+	// encoded size, SendMsgs splits larger batches into several txs. 0 is no limit.
+	MaxMsgsPerTx int `yaml:"max-msgs-per-tx,omitempty"`
+	MaxTxBytes   int `yaml:"max-tx-bytes,omitempty"`
+
+	// BackupRPCAddrs are RPC addresses used in order when RPCAddr is unhealthy
+	BackupRPCAddrs []string `yaml:"backup-rpc-addrs,omitempty"`
+
+	// Witnesses are the RPC addresses of the full nodes the lite client
+	// cross-checks every header from RPCAddr against
+	Witnesses []string `yaml:"witnesses,omitempty"`
+
 	Keybase keys.Keybase
-	Client  *rpcclient.HTTP
+	Client  *RPCClient
 	Cdc     *codec.Codec
 
 	address sdk.AccAddress
 	logger  log.Logger
+	feeBump sdk.Dec
+
+	// The lite client and its database handle are shared by everything using
+	// the chain for the lifetime of the process. liteMtx serializes use of the
+	// lite client, liteDBMtx guards opening and closing the database.
+	liteMtx   sync.Mutex
+	lc        *lite.Client
+	liteDBMtx sync.Mutex
+	liteDB    *dbm.GoLevelDB
+	witnesses []litep.Provider
+
+	// haltErr is set once the lite client has seen conflicting headers, no
+	// more relaying happens on the chain after that. The halt is persisted, it
+	// is loaded from disk on first use.
+	haltMtx    sync.Mutex
+	haltErr    error
+	haltLoaded bool
+
+	// balance is the relayer account's balance as of the last CheckBalance,
+	// no txs are sent while it is paused
+	balanceMtx sync.Mutex
+	balance    BalanceStatus
+
+	// notifier is sent notifications about the chain, txFailures counts the
+	// txs in a row that failed on it
+	notifier      *Notifier
+	txFailuresMtx sync.Mutex
+	txFailures    int
 }
 
 // Chains is a collection of Chain
 type Chains []*Chain
 
+// Close releases the resources held by each chain, it must be called on shutdown
+func (c Chains) Close() error {
+	var out error
+	for _, chain := range c {
+		if err := chain.CloseLiteDB(); err != nil {
+			out = fmt.Errorf("%s err: %w", chain.ChainID, err)
+		}
+		if err := chain.Client.Stop(); err != nil {
+			out = fmt.Errorf("%s err: %w", chain.ChainID, err)
+		}
+	}
+	return out
+}
+
 // Exists Returns true if the chain is configured
 func (c Chains) Exists(chainID string) bool {
 	for _, chain := range c {
@@ -109,23 +285,70 @@ func (c Chains) GetChains(chainIDs ...string) (map[string]*Chain, error) {
 	return out, nil
 }
 
-func (c *Chain) BuildAndSignTx(datagram []sdk.Msg) ([]byte, error) {
+// BuildAndSignTx builds a tx with the given gas limit and fee and signs it
+func (c *Chain) BuildAndSignTx(datagram []sdk.Msg, gas uint64, fees sdk.Coins) ([]byte, error) {
 	// Fetch account and sequence numbers for the account
 	acc, err := auth.NewAccountRetriever(c).GetAccount(c.MustGetAddress())
 	if err != nil {
 		return nil, err
 	}
 
+	return c.txBuilder(acc, gas, fees).BuildAndSign(c.Key, ckeys.DefaultKeyPass, datagram)
+}
+
+func (c *Chain) txBuilder(acc authexported.Account, gas uint64, fees sdk.Coins) auth.TxBuilder {
 	return auth.NewTxBuilder(
 		auth.DefaultTxEncoder(c.Cdc), acc.GetAccountNumber(),
-		acc.GetSequence(), c.Gas, c.GasAdjustment, false, c.ChainID,
-		c.Memo, sdk.NewCoins(), c.GasPrices).WithKeybase(c.Keybase).
-		BuildAndSign(c.Key, ckeys.DefaultKeyPass, datagram)
+		acc.GetSequence(), gas, c.GasAdjustment, false, c.ChainID,
+		c.Memo, fees, sdk.DecCoins{}).WithKeybase(c.Keybase)
 }
 
-// BroadcastTxCommit takes the marshaled transaction bytes and broadcasts them
+// BroadcastTxCommit takes the marshaled transaction bytes and broadcasts them,
+// then waits up to TxConfirmationTimeout for the transaction to be committed
 func (c *Chain) BroadcastTxCommit(txBytes []byte) (sdk.TxResponse, error) {
-	return context.CLIContext{Client: c.Client}.BroadcastTxCommit(txBytes)
+	bres, err := c.Client.BroadcastTxSync(txBytes)
+	if errRes := context.CheckTendermintError(err, txBytes); errRes != nil {
+		return *errRes, nil
+	}
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+
+	res := sdk.NewResponseFormatBroadcastTx(bres)
+	if res.Code != 0 {
+		return res, nil
+	}
+
+	return c.WaitForTx(res.TxHash, c.TxConfirmationTimeout)
+}
+
+// WaitForTx polls the chain for the transaction with the given hash until it has
+// been committed or the timeout passes
+func (c *Chain) WaitForTx(txHash string, timeout time.Duration) (sdk.TxResponse, error) {
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(txPollPeriod)
+	defer ticker.Stop()
+	for {
+		resTx, err := c.Client.Tx(hash, false)
+		if err == nil {
+			resBlock, err := c.Client.Block(&resTx.Height)
+			if err != nil {
+				return sdk.TxResponse{}, err
+			}
+			return c.formatTxResult(resTx, resBlock)
+		}
+
+		if time.Now().After(deadline) {
+			return sdk.TxResponse{TxHash: txHash}, fmt.Errorf("%w: %s on chain %s after %s: %v", ErrTxNotCommitted, txHash, c.ChainID, timeout, err)
+		}
+
+		<-ticker.C
+	}
 }
 
 // KeysDir returns the path to the keys for this chain
@@ -173,6 +396,11 @@ func (p Path) String() string {
 	return fmt.Sprintf("%s -> %s", p.Src.String(), p.Dst.String())
 }
 
+// Reverse returns the path with its ends swapped
+func (p Path) Reverse() Path {
+	return Path{Src: p.Dst, Dst: p.Src}
+}
+
 // PathEnd represents the local connection identifers for a relay path
 // The path is set on the chain before performing operations
 type PathEnd struct {
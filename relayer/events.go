@@ -0,0 +1,190 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	clientTypes "github.com/cosmos/cosmos-sdk/x/ibc/02-client/types"
+	connTypes "github.com/cosmos/cosmos-sdk/x/ibc/03-connection/types"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	xferTypes "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+const (
+	eventSubscriber = "relayer"
+	newBlockQuery   = "tm.event='NewBlock'"
+	txQuery         = "tm.event='Tx'"
+)
+
+// RelayEvent signals that a chain may have work for the relayer, either because
+// a transaction with IBC events was committed or because events may have been missed
+type RelayEvent struct {
+	ChainID string
+	Height  int64
+
+	// Types are the IBC event types of the transaction, empty if Missed
+	Types []string
+
+	// Missed is set when the relayer was not subscribed for some blocks, e.g.
+	// after (re)connecting, and the chain's paths need to be scanned in full
+	Missed bool
+}
+
+func (e RelayEvent) String() string {
+	if e.Missed {
+		return fmt.Sprintf("chain %s: events may have been missed, scanning paths", e.ChainID)
+	}
+	return fmt.Sprintf("chain %s: %s at height %d", e.ChainID, strings.Join(e.Types, ", "), e.Height)
+}
+
+// ibcEventTypes are the tx event types the relayer may have to act on
+var ibcEventTypes = []string{
+	clientTypes.EventTypeCreateClient,
+	clientTypes.EventTypeUpdateClient,
+	connTypes.EventTypeConnectionOpenInit,
+	connTypes.EventTypeConnectionOpenTry,
+	connTypes.EventTypeConnectionOpenAck,
+	connTypes.EventTypeConnectionOpenConfirm,
+	chanTypes.EventTypeChannelOpenInit,
+	chanTypes.EventTypeChannelOpenTry,
+	chanTypes.EventTypeChannelOpenAck,
+	chanTypes.EventTypeChannelOpenConfirm,
+	chanTypes.EventTypeChannelCloseInit,
+	chanTypes.EventTypeChannelCloseConfirm,
+}
+
+// ibcTxEventTypes returns the IBC event types found in the events of a tx. Packets
+// don't emit events of their own yet, sends and receives of ICS20 transfer packets
+// are recognised by the module of their message instead.
+func ibcTxEventTypes(events map[string][]string) []string {
+	var out []string
+	for _, typ := range ibcEventTypes {
+		for key := range events {
+			if strings.HasPrefix(key, typ+".") {
+				out = append(out, typ)
+				break
+			}
+		}
+	}
+
+	for _, module := range events[sdk.EventTypeMessage+"."+sdk.AttributeKeyModule] {
+		if module == xferTypes.AttributeValueCategory {
+			out = append(out, module)
+			break
+		}
+	}
+
+	return out
+}
+
+// SubscribeRelayEvents sends a RelayEvent for every transaction with IBC events
+// committed on the chain until ctx is done. Whenever the subscription is (re)made
+// or blocks are skipped a Missed event is sent so the caller can fall back to
+// scanning. If no block arrives for staleAfter, or the RPC client fails over to
+// another endpoint, the subscription is made again.
+func (c *Chain) SubscribeRelayEvents(ctx context.Context, events chan<- RelayEvent, staleAfter time.Duration) {
+	for {
+		if err := c.relayEvents(ctx, events, staleAfter); err != nil {
+			c.logger.Error("event subscription failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(staleAfter):
+		}
+	}
+}
+
+func (c *Chain) relayEvents(ctx context.Context, events chan<- RelayEvent, staleAfter time.Duration) error {
+	addr := c.Client.ActiveAddr()
+	defer func() {
+		if err := c.Client.UnsubscribeAll(addr, eventSubscriber); err != nil {
+			c.logger.Error("failed to unsubscribe", "addr", addr, "err", err)
+		}
+	}()
+
+	blocks, err := c.Client.Subscribe(ctx, addr, eventSubscriber, newBlockQuery)
+	if err != nil {
+		return err
+	}
+
+	txs, err := c.Client.Subscribe(ctx, addr, eventSubscriber, txQuery)
+	if err != nil {
+		return err
+	}
+
+	// Anything could have happened while we weren't subscribed
+	if !sendRelayEvent(ctx, events, RelayEvent{ChainID: c.ChainID, Missed: true}) {
+		return nil
+	}
+
+	ticker := time.NewTicker(staleAfter)
+	defer ticker.Stop()
+
+	var last int64
+	var seen bool
+	for {
+		var ev RelayEvent
+		select {
+		case <-ctx.Done():
+			return nil
+		case res := <-blocks:
+			height, ok := newBlockHeight(res)
+			if !ok {
+				continue
+			}
+			missed := last != 0 && height > last+1
+			last, seen = height, true
+			if !missed {
+				continue
+			}
+			ev = RelayEvent{ChainID: c.ChainID, Height: height, Missed: true}
+		case res := <-txs:
+			types := ibcTxEventTypes(res.Events)
+			if len(types) == 0 {
+				continue
+			}
+			ev = RelayEvent{ChainID: c.ChainID, Types: types}
+			if tx, ok := res.Data.(tmtypes.EventDataTx); ok {
+				ev.Height = tx.Height
+			}
+		case <-ticker.C:
+			if !seen {
+				return fmt.Errorf("no new blocks from %s in %s, resubscribing", addr, staleAfter)
+			}
+			if active := c.Client.ActiveAddr(); active != addr {
+				return fmt.Errorf("RPC endpoint changed from %s to %s, resubscribing", addr, active)
+			}
+			seen = false
+			continue
+		}
+
+		if !sendRelayEvent(ctx, events, ev) {
+			return nil
+		}
+	}
+}
+
+// sendRelayEvent returns false if ctx was done before ev could be sent
+func sendRelayEvent(ctx context.Context, events chan<- RelayEvent, ev RelayEvent) bool {
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func newBlockHeight(res ctypes.ResultEvent) (int64, bool) {
+	block, ok := res.Data.(tmtypes.EventDataNewBlock)
+	if !ok || block.Block == nil {
+		return 0, false
+	}
+	return block.Block.Height, true
+}
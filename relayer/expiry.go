@@ -0,0 +1,159 @@
+package relayer
+
+import (
+	"fmt"
+	"time"
+)
+
+// ClientExpiry describes how close the client on a chain is to outliving the
+// trusting period of the chain it tracks. The clients of this version of the SDK
+// store neither a trusting period nor a timestamp, so the time of the client's
+// latest height is taken from the header of the tracked chain at that height and
+// the trusting period from the tracked chain's config, the same one its lite
+// client uses.
+type ClientExpiry struct {
+	ChainID        string        `json:"chain-id" yaml:"chain-id"`
+	ClientID       string        `json:"client-id" yaml:"client-id"`
+	TrackedChainID string        `json:"tracked-chain-id" yaml:"tracked-chain-id"`
+	LatestHeight   uint64        `json:"latest-height" yaml:"latest-height"`
+	LatestTime     time.Time     `json:"latest-time" yaml:"latest-time"`
+	TrustingPeriod time.Duration `json:"trusting-period" yaml:"trusting-period"`
+	Frozen         bool          `json:"frozen" yaml:"frozen"`
+}
+
+// ExpiresAt returns the time after which the client can no longer be updated
+func (e ClientExpiry) ExpiresAt() time.Time {
+	return e.LatestTime.Add(e.TrustingPeriod)
+}
+
+// Elapsed returns the fraction of the trusting period that has passed at now
+// since the client's latest update
+func (e ClientExpiry) Elapsed(now time.Time) float64 {
+	return float64(now.Sub(e.LatestTime)) / float64(e.TrustingPeriod)
+}
+
+func (e ClientExpiry) String() string {
+	return fmt.Sprintf("client %s on %s tracking %s: last updated to height %d at %s, expires at %s",
+		e.ClientID, e.ChainID, e.TrackedChainID, e.LatestHeight, e.LatestTime.Format(time.RFC3339), e.ExpiresAt().Format(time.RFC3339))
+}
+
+// attributes returns e as notification attributes
+func (e ClientExpiry) attributes() []string {
+	return []string{"client-id", e.ClientID, "tracked-chain-id", e.TrackedChainID,
+		"latest-height", fmt.Sprint(e.LatestHeight), "expires-at", e.ExpiresAt().Format(time.RFC3339)}
+}
+
+// QueryClientExpiry returns how close the client on the chain's path end, which
+// tracks dst, is to expiry
+func (c *Chain) QueryClientExpiry(dst *Chain) (ClientExpiry, error) {
+	cs, err := c.QueryClientState()
+	if err != nil {
+		return ClientExpiry{}, err
+	} else if cs.ClientState == nil {
+		return ClientExpiry{}, fmt.Errorf("no client %s on %s", c.PathEnd.ClientID, c.ChainID)
+	}
+
+	height := cs.ClientState.GetLatestHeight()
+	h, err := dst.QueryHeaderAtHeight(int64(height))
+	if err != nil {
+		return ClientExpiry{}, err
+	}
+
+	return ClientExpiry{
+		ChainID:        c.ChainID,
+		ClientID:       c.PathEnd.ClientID,
+		TrackedChainID: dst.ChainID,
+		LatestHeight:   height,
+		LatestTime:     h.Time,
+		TrustingPeriod: dst.TrustingPeriod,
+		Frozen:         cs.ClientState.IsFrozen(),
+	}, nil
+}
+
+// UpdateExpiringClients updates the clients on both ends of the paths once more
+// than threshold of their trusting period has passed since their last update, so
+// clients on paths without traffic don't expire. Clients close to or past expiry
+// are warned about.
+func UpdateExpiringClients(c Chains, paths []Path, threshold float64) error {
+	for _, path := range paths {
+		for _, p := range []Path{path, path.Reverse()} {
+			src, err := c.GetChain(p.Src.ChainID)
+			if err != nil {
+				return err
+			}
+
+			dst, err := c.GetChain(p.Dst.ChainID)
+			if err != nil {
+				return err
+			}
+
+			if err = haltedErr(src, dst); err != nil {
+				src.logger.Error("not checking clients for expiry", "path", p, "err", err)
+				continue
+			}
+
+			if err = src.setPath(&p.Src); err != nil {
+				return err
+			}
+
+			if err = dst.setPath(&p.Dst); err != nil {
+				return err
+			}
+
+			if err = src.updateExpiringClient(dst, threshold); err != nil {
+				src.logger.Error("failed to check client for expiry", "client-id", src.PathEnd.ClientID, "err", err)
+			}
+		}
+	}
+	return nil
+}
+
+// updateExpiringClient updates the client on the chain's path end with the latest
+// header of dst if more than threshold of its trusting period has passed
+func (c *Chain) updateExpiringClient(dst *Chain, threshold float64) error {
+	e, err := c.QueryClientExpiry(dst)
+	if err != nil {
+		return err
+	}
+	observeClientExpiry(e)
+
+	logger := c.logger.With("client-id", e.ClientID, "tracked-chain-id", e.TrackedChainID,
+		"height", e.LatestHeight, "expires-at", e.ExpiresAt().Format(time.RFC3339))
+	now := time.Now()
+	expiredKey := fmt.Sprintf("%s/%s/%s", NotifyClientExpired, e.ChainID, e.ClientID)
+	if e.Elapsed(now) < 1 {
+		c.notifier.Resolve(expiredKey)
+	}
+
+	switch {
+	case e.Frozen:
+		logger.Error("client is frozen after misbehaviour, it can't be updated")
+		return nil
+	case e.Elapsed(now) >= 1:
+		logger.Error("client has expired and must be replaced")
+		c.notifyOnce(expiredKey, NotifyClientExpired,
+			fmt.Sprintf("%s, the client has expired and must be replaced", e), e.attributes()...)
+		return nil
+	case e.Elapsed(now) < threshold:
+		return nil
+	}
+
+	// the logger has no warn level, an imminent expiry must not go unnoticed
+	logger.Error("client is close to expiry, updating it", "expires-in", e.ExpiresAt().Sub(now).Round(time.Second))
+	c.notify(NotifyClientExpiring, fmt.Sprintf("%s, updating it", e), e.attributes()...)
+
+	h, err := dst.UpdateLiteWithHeader()
+	if err != nil {
+		return err
+	}
+
+	res, err := c.SendMsg(c.UpdateClient(h))
+	if err != nil {
+		return err
+	} else if res.Code != 0 {
+		return fmt.Errorf("update client tx %s failed: %s", res.TxHash, res.RawLog)
+	}
+
+	logger.Info("updated client", "height", h.Height, "tx-hash", res.TxHash)
+	return nil
+}
@@ -0,0 +1,179 @@
+package relayer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	ckeys "github.com/cosmos/cosmos-sdk/client/keys"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	authclient "github.com/cosmos/cosmos-sdk/x/auth/client"
+)
+
+// maxFeeBumps is how many times the fee of a tx is bumped before giving up
+const maxFeeBumps = 5
+
+// ErrTxNotCommitted is returned when a broadcast tx isn't committed in time
+var ErrTxNotCommitted = errors.New("tx not committed")
+
+// Fee returns the fee paid by a tx using gas after its fee has been bumped bumps
+// times. The fee is the chain's fixed Fees if set, GasPrices times gas otherwise,
+// multiplied by FeeBump for every bump and capped at MaxFee in each of its denoms.
+func (c *Chain) Fee(gas uint64, bumps int) sdk.Coins {
+	fee := c.Fees
+	if fee.Empty() {
+		fee = sdk.NewCoins()
+		for _, gp := range c.GasPrices {
+			fee = fee.Add(sdk.NewCoin(gp.Denom, gp.Amount.MulInt64(int64(gas)).Ceil().RoundInt()))
+		}
+	}
+
+	factor := c.feeBump.Power(uint64(bumps))
+	out := sdk.NewCoins()
+	for _, coin := range fee {
+		amount := coin.Amount.ToDec().Mul(factor).Ceil().RoundInt()
+		if max := c.MaxFee.AmountOf(coin.Denom); max.IsPositive() && amount.GT(max) {
+			amount = max
+		}
+		out = out.Add(sdk.NewCoin(coin.Denom, amount))
+	}
+	return out
+}
+
+// gasFor returns the gas limit for a tx with msgs, the chain's Gas if set,
+// otherwise simulated and multiplied by its GasAdjustment
+func (c *Chain) gasFor(msgs []sdk.Msg) (uint64, error) {
+	if c.Gas > 0 {
+		return c.Gas, nil
+	}
+
+	acc, err := auth.NewAccountRetriever(c).GetAccount(c.MustGetAddress())
+	if err != nil {
+		return 0, err
+	}
+
+	txBytes, err := c.txBuilder(acc, 0, sdk.NewCoins()).BuildTxForSim(msgs)
+	if err != nil {
+		return 0, err
+	}
+
+	_, adjusted, err := authclient.CalculateGas(c.QueryWithData, c.Cdc, txBytes, c.GasAdjustment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate tx on %s: %w", c.ChainID, err)
+	}
+	return adjusted, nil
+}
+
+// sendTx signs msgs into a tx and broadcasts it, waiting until it's committed.
+// If the tx is rejected for insufficient fees, or isn't committed within the
+// TxConfirmationTimeout because it sits in the mempool, it is signed again with
+// the same account sequence and a bumped fee and resent. Only one of the txs
+// sharing the sequence can be committed. Tendermint doesn't replace txs in its
+// mempool, so while a stuck tx is still there the resent tx is rejected and the
+// stuck one is waited for instead. No tx is sent while the chain is paused for
+// lack of funds, see CheckBalance.
+func (c *Chain) sendTx(msgs []sdk.Msg) (res sdk.TxResponse, err error) {
+	// fee is the fee of the tx whose response is returned
+	var fee sdk.Coins
+	defer func() {
+		c.logTx(msgs, res, fee, err)
+		c.observeTx(res, fee, err)
+		c.recordTxResult(msgs, res, err)
+	}()
+
+	if err = c.paused(); err != nil {
+		return sdk.TxResponse{}, err
+	}
+
+	gas, err := c.gasFor(msgs)
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+
+	// every tx is signed with the sequence of the account before the first one
+	acc, err := auth.NewAccountRetriever(c).GetAccount(c.MustGetAddress())
+	if err != nil {
+		return sdk.TxResponse{}, err
+	}
+
+	// stuck are the txs broadcast before that weren't committed in time
+	var stuck []sentTx
+	for bumps := 0; ; bumps++ {
+		fee = c.Fee(gas, bumps)
+		txBytes, err := c.txBuilder(acc, gas, fee).BuildAndSign(c.Key, ckeys.DefaultKeyPass, msgs)
+		if err != nil {
+			return sdk.TxResponse{}, err
+		}
+
+		res, err = c.BroadcastTxCommit(txBytes)
+		canBump := bumps < maxFeeBumps && c.FeeBump > 1 && !c.Fee(gas, bumps+1).IsEqual(fee)
+		switch {
+		case err == nil && res.Codespace == sdkerrors.RootCodespace && res.Code == sdkerrors.ErrInsufficientFunds.ABCICode():
+			c.logger.Error("tx rejected with insufficient funds, not sending txs until it is funded", "msgs", msgTypes(msgs), "fee", fee)
+			c.pause()
+			return res, nil
+
+		case err == nil && res.Codespace == sdkerrors.RootCodespace && res.Code == sdkerrors.ErrInsufficientFee.ABCICode() && canBump:
+			c.logger.Info("tx rejected with insufficient fee, resending with a bumped fee", "msgs", msgTypes(msgs), "fee", fee, "new-fee", c.Fee(gas, bumps+1))
+
+		case errors.Is(err, ErrTxNotCommitted):
+			stuck = append(stuck, sentTx{hash: res.TxHash, fee: fee})
+			if committed, committedFee, ok := c.committedTx(stuck); ok {
+				fee = committedFee
+				return committed, nil
+			} else if !canBump {
+				return res, err
+			}
+			c.logger.Info("tx not committed in time, resending with the same sequence and a bumped fee", "msgs", msgTypes(msgs),
+				"tx-hash", res.TxHash, "fee", fee, "new-fee", c.Fee(gas, bumps+1))
+
+		case err == nil && res.Code != 0 && res.Height == 0 && len(stuck) > 0:
+			// most likely a stuck tx still holds the sequence in the mempool
+			c.logger.Info("resent tx rejected, waiting for the stuck tx", "msgs", msgTypes(msgs), "tx-hash", stuck[len(stuck)-1].hash, "err", res.RawLog)
+			return c.waitForStuck(stuck, &fee)
+
+		default:
+			return res, err
+		}
+	}
+}
+
+// sentTx is a tx broadcast by sendTx and the fee it paid
+type sentTx struct {
+	hash string
+	fee  sdk.Coins
+}
+
+// committedTx returns the response of the first of the txs that has been committed
+func (c *Chain) committedTx(txs []sentTx) (sdk.TxResponse, sdk.Coins, bool) {
+	for _, tx := range txs {
+		if res, err := c.WaitForTx(tx.hash, 0); err == nil {
+			return res, tx.fee, true
+		}
+	}
+	return sdk.TxResponse{}, nil, false
+}
+
+// waitForStuck waits up to TxConfirmationTimeout for one of the stuck txs to be
+// committed, setting fee to the fee it paid
+func (c *Chain) waitForStuck(stuck []sentTx, fee *sdk.Coins) (sdk.TxResponse, error) {
+	last := stuck[len(stuck)-1]
+	deadline := time.Now().Add(c.TxConfirmationTimeout)
+	ticker := time.NewTicker(txPollPeriod)
+	defer ticker.Stop()
+	for {
+		if res, committedFee, ok := c.committedTx(stuck); ok {
+			*fee = committedFee
+			return res, nil
+		}
+
+		if time.Now().After(deadline) {
+			*fee = last.fee
+			return sdk.TxResponse{TxHash: last.hash}, fmt.Errorf("%w: %s on chain %s after %s", ErrTxNotCommitted, last.hash, c.ChainID, c.TxConfirmationTimeout)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,67 @@
+package relayer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestFee(t *testing.T) {
+	c := &Chain{
+		GasPrices: sdk.NewDecCoins(sdk.NewDecCoinFromDec("stake", sdk.NewDecWithPrec(25, 3))),
+		MaxFee:    sdk.NewCoins(sdk.NewInt64Coin("stake", 5000)),
+		FeeBump:   1.5,
+		feeBump:   sdk.NewDecWithPrec(15, 1),
+	}
+
+	cases := []struct {
+		name  string
+		fees  sdk.Coins
+		gas   uint64
+		bumps int
+		want  sdk.Coins
+	}{
+		{"gas price times gas", nil, 100001, 0, sdk.NewCoins(sdk.NewInt64Coin("stake", 2501))},
+		{"bumped", nil, 100000, 1, sdk.NewCoins(sdk.NewInt64Coin("stake", 3750))},
+		{"capped at max fee", nil, 100000, 3, sdk.NewCoins(sdk.NewInt64Coin("stake", 5000))},
+		{"fixed fee", sdk.NewCoins(sdk.NewInt64Coin("stake", 100)), 100000, 1, sdk.NewCoins(sdk.NewInt64Coin("stake", 150))},
+	}
+
+	for _, tc := range cases {
+		c.Fees = tc.fees
+		if got := c.Fee(tc.gas, tc.bumps); !got.IsEqual(tc.want) {
+			t.Errorf("%s: Fee(%d, %d) = %s, want %s", tc.name, tc.gas, tc.bumps, got, tc.want)
+		}
+	}
+}
+
+func TestNewChainFeeBump(t *testing.T) {
+	home, err := ioutil.TempDir("", "relayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	opts := ChainOptions{
+		Key: "testkey", ChainID: "ibc0", RPCAddr: "http://localhost:26657", HomePath: home,
+		TrustingPeriod: "336h", LiteUpdateInterval: "5s", RPCTimeout: "10s", TxConfirmationTimeout: "1m", MaxBlockAge: "1m",
+	}
+
+	for bump, want := range map[float64]sdk.Dec{0: sdk.ZeroDec(), 1: sdk.OneDec(), 1.25: sdk.NewDecWithPrec(125, 2)} {
+		opts.FeeBump = bump
+		c, err := NewChain(opts, nil, log.NewNopLogger())
+		if err != nil {
+			t.Errorf("fee bump %v: %v", bump, err)
+		} else if !c.feeBump.Equal(want) {
+			t.Errorf("fee bump %v parsed as %s, want %s", bump, c.feeBump, want)
+		}
+	}
+
+	opts.FeeBump = 0.5
+	if _, err := NewChain(opts, nil, log.NewNopLogger()); err == nil {
+		t.Error("expected an error for a fee bump below 1")
+	}
+}
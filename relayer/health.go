@@ -0,0 +1,37 @@
+package relayer
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrRPCUnavailable is returned by Ready when no RPC endpoint of the chain answers
+	ErrRPCUnavailable = errors.New("rpc unavailable")
+
+	// ErrChainHalted is returned by Ready when the chain has been halted, see Halted
+	ErrChainHalted = errors.New("chain halted")
+)
+
+// Ready returns nil if the chain can be relayed on: its RPC endpoint is reachable
+// and caught up, its lite client has been initialized and it hasn't been halted.
+// The error wraps ErrRPCUnavailable, ErrLiteNotInitialized or ErrChainHalted, or
+// is the lite client's error.
+func (c *Chain) Ready() error {
+	if _, err := c.QueryLatestHeight(); err != nil {
+		return fmt.Errorf("%w: %v", ErrRPCUnavailable, err)
+	}
+
+	height, err := c.GetLatestLiteHeight()
+	switch {
+	case err != nil:
+		return err
+	case height == -1:
+		return fmt.Errorf("%w for chain %s", ErrLiteNotInitialized, c.ChainID)
+	}
+
+	if err = c.Halted(); err != nil {
+		return fmt.Errorf("%w: %v", ErrChainHalted, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package relayer
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// Log formats supported by NewLogger
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// NewLogger returns a logger writing lines of format, LogFormatText or
+// LogFormatJSON, to w, dropping those below level: "debug", "info" or "error"
+func NewLogger(w io.Writer, format, level string) (log.Logger, error) {
+	var logger log.Logger
+	switch format {
+	case LogFormatText:
+		logger = log.NewTMLogger(log.NewSyncWriter(w))
+	case LogFormatJSON:
+		logger = log.NewTMJSONLogger(log.NewSyncWriter(w)).With("ts", kitlog.DefaultTimestampUTC)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be %s or %s", format, LogFormatText, LogFormatJSON)
+	}
+
+	opt, err := log.AllowLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return log.NewFilter(logger, opt), nil
+}
+
+// Logger returns the chain's logger, every line of which carries its chain-id
+func (c *Chain) Logger() log.Logger {
+	return c.logger
+}
+
+// logTx logs the result of a tx with msgs sent to the chain
+func (c *Chain) logTx(msgs []sdk.Msg, res sdk.TxResponse, fee sdk.Coins, err error) {
+	logger := c.logger.With("msgs", msgTypes(msgs), "tx-hash", res.TxHash, "fee", fee)
+	switch {
+	case err != nil:
+		logger.Error("failed to send tx", "err", err)
+	case res.Code != 0:
+		logger.Error("tx failed", "height", res.Height, "code", res.Code, "codespace", res.Codespace, "log", res.RawLog)
+	default:
+		logger.Info("tx committed", "height", res.Height, "gas-used", res.GasUsed)
+	}
+}
+
+// msgTypes returns the types of msgs for logging, e.g. "MsgUpdateClient,MsgPacket".
+// The type names are used as the Type method of some msgs depends on their data.
+func msgTypes(msgs []sdk.Msg) string {
+	types := make([]string, len(msgs))
+	for i, msg := range msgs {
+		types[i] = reflect.TypeOf(msg).Name()
+	}
+	return strings.Join(types, ",")
+}
@@ -0,0 +1,230 @@
+package relayer
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	xferTypes "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "relayer"
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	pathLabels = []string{"src_chain_id", "src_channel_id", "dst_chain_id", "dst_channel_id"}
+
+	packetsRelayed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "packets_relayed_total",
+		Help:      "Packets sent on src and delivered to dst.",
+	}, pathLabels)
+	acksRelayed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "acks_relayed_total",
+		Help:      "Acknowledgements written on src and delivered to dst.",
+	}, pathLabels)
+	timeoutsRelayed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "timeouts_relayed_total",
+		Help:      "Packets sent on dst that timed out on src, timed out on dst.",
+	}, pathLabels)
+
+	txs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "txs_total",
+		Help:      "Txs sent to a chain by result, success or the class of error.",
+	}, []string{"chain_id", "result"})
+	gasUsed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "gas_used_total",
+		Help:      "Gas used by the committed txs sent to a chain.",
+	}, []string{"chain_id"})
+	feesSpent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "fees_spent_total",
+		Help:      "Fees paid by the committed txs sent to a chain.",
+	}, []string{"chain_id", "denom"})
+
+	chainHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "chain_height",
+		Help:      "Latest height of a chain according to its active RPC endpoint.",
+	}, []string{"chain_id"})
+	liteHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "lite_height",
+		Help:      "Latest height trusted by a chain's lite client.",
+	}, []string{"chain_id"})
+
+	relayDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_duration_seconds",
+		Help:      "Duration of a relay round over the paths.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+	})
+
+	clientExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "client_expiry_timestamp_seconds",
+		Help:      "Unix time at which a client expires unless it is updated.",
+	}, []string{"chain_id", "client_id", "tracked_chain_id"})
+
+	balance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "balance",
+		Help:      "Balance of the relayer's account on a chain.",
+	}, []string{"chain_id", "denom"})
+	balanceLow = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "balance_low",
+		Help:      "1 while the relayer's balance on a chain is below its min-balance.",
+	}, []string{"chain_id"})
+	balancePaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "balance_paused",
+		Help:      "1 while no txs are sent to a chain as the relayer can't pay fees.",
+	}, []string{"chain_id"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		packetsRelayed, acksRelayed, timeoutsRelayed,
+		txs, gasUsed, feesSpent,
+		chainHeight, liteHeight,
+		relayDuration, clientExpiry,
+		balance, balanceLow, balancePaused,
+	)
+}
+
+// MetricsHandler returns the handler serving the relayer's metrics to Prometheus
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// Values of the result label of relayer_txs_total
+const (
+	txSuccess           = "success"
+	txInsufficientFee   = "insufficient_fee"
+	txInsufficientFunds = "insufficient_funds"
+	txOutOfGas          = "out_of_gas"
+	txInvalidSequence   = "invalid_sequence"
+	txNotCommitted      = "not_committed"
+	txPaused            = "paused"
+	txFailed            = "failed"
+	txError             = "error"
+)
+
+// txResult returns the class of the result of a tx for relayer_txs_total
+func txResult(res sdk.TxResponse, err error) string {
+	switch {
+	case errors.Is(err, ErrInsufficientBalance):
+		return txPaused
+	case errors.Is(err, ErrTxNotCommitted):
+		return txNotCommitted
+	case err != nil:
+		return txError
+	case res.Code == 0:
+		return txSuccess
+	case res.Codespace != sdkerrors.RootCodespace:
+		return txFailed
+	}
+
+	switch res.Code {
+	case sdkerrors.ErrInsufficientFee.ABCICode():
+		return txInsufficientFee
+	case sdkerrors.ErrInsufficientFunds.ABCICode():
+		return txInsufficientFunds
+	case sdkerrors.ErrOutOfGas.ABCICode():
+		return txOutOfGas
+	case sdkerrors.ErrInvalidSequence.ABCICode():
+		return txInvalidSequence
+	default:
+		return txFailed
+	}
+}
+
+// observeTx records the result of a tx sent to the chain, and the gas and fee it
+// used if it was committed
+func (c *Chain) observeTx(res sdk.TxResponse, fee sdk.Coins, err error) {
+	txs.WithLabelValues(c.ChainID, txResult(res, err)).Inc()
+	if err != nil || res.Height == 0 {
+		return
+	}
+
+	gasUsed.WithLabelValues(c.ChainID).Add(float64(res.GasUsed))
+	for _, coin := range fee {
+		feesSpent.WithLabelValues(c.ChainID, coin.Denom).Add(coinAmount(coin))
+	}
+}
+
+// observeRelayed counts the packets, acks and timeouts delivered to dst by msgs,
+// which were relayed from src
+func observeRelayed(src, dst *PathEnd, msgs []sdk.Msg) {
+	labels := []string{src.ChainID, src.ChannelID, dst.ChainID, dst.ChannelID}
+	for _, msg := range msgs {
+		switch msg.(type) {
+		case chanTypes.MsgPacket, xferTypes.MsgRecvPacket:
+			packetsRelayed.WithLabelValues(labels...).Inc()
+		case chanTypes.MsgAcknowledgement:
+			acksRelayed.WithLabelValues(labels...).Inc()
+		case chanTypes.MsgTimeout:
+			timeoutsRelayed.WithLabelValues(labels...).Inc()
+		}
+	}
+}
+
+// observeHeights records the latest height of the chain and of its lite client
+func (c *Chain) observeHeights() {
+	if h, err := c.QueryLatestHeight(); err == nil {
+		chainHeight.WithLabelValues(c.ChainID).Set(float64(h))
+	}
+	if h, err := c.GetLatestLiteHeight(); err == nil {
+		liteHeight.WithLabelValues(c.ChainID).Set(float64(h))
+	}
+}
+
+// observeBalance records the balance of the relayer's account as of a check, the
+// denoms of the previous balance that are gone are set to 0
+func (c *Chain) observeBalance(prev, status BalanceStatus) {
+	for _, coin := range prev.Balance {
+		balance.WithLabelValues(c.ChainID, coin.Denom).Set(0)
+	}
+	for _, coin := range status.Balance {
+		balance.WithLabelValues(c.ChainID, coin.Denom).Set(coinAmount(coin))
+	}
+	balanceLow.WithLabelValues(c.ChainID).Set(boolGauge(status.Low))
+	balancePaused.WithLabelValues(c.ChainID).Set(boolGauge(status.Paused))
+}
+
+// observeClientExpiry records when the client described by e expires
+func observeClientExpiry(e ClientExpiry) {
+	clientExpiry.WithLabelValues(e.ChainID, e.ClientID, e.TrackedChainID).Set(float64(e.ExpiresAt().Unix()))
+}
+
+// observeRelay records the duration of a relay round that started at start
+func observeRelay(start time.Time) {
+	relayDuration.Observe(time.Since(start).Seconds())
+}
+
+// coinAmount returns the amount of coin as a float, which may lose precision
+func coinAmount(coin sdk.Coin) float64 {
+	f, _ := new(big.Float).SetInt(coin.Amount.BigInt()).Float64()
+	return f
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
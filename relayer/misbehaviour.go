@@ -0,0 +1,273 @@
+package relayer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	clientExported "github.com/cosmos/cosmos-sdk/x/ibc/02-client/exported"
+	clientTypes "github.com/cosmos/cosmos-sdk/x/ibc/02-client/types"
+	tmclient "github.com/cosmos/cosmos-sdk/x/ibc/07-tendermint"
+	lite "github.com/tendermint/tendermint/lite2"
+)
+
+// Misbehaviour is a consensus state stored by a client that conflicts with the
+// header of the tracked chain at the same height trusted by the relayer's lite client
+type Misbehaviour struct {
+	ChainID        string                  `json:"chain-id" yaml:"chain-id"`
+	ClientID       string                  `json:"client-id" yaml:"client-id"`
+	TrackedChainID string                  `json:"tracked-chain-id" yaml:"tracked-chain-id"`
+	Height         uint64                  `json:"height" yaml:"height"`
+	Stored         tmclient.ConsensusState `json:"stored" yaml:"stored"`
+	Trusted        *tmclient.Header        `json:"trusted" yaml:"trusted"`
+
+	// Conflicting is the header the client was updated with at Height, nil if
+	// it couldn't be found, e.g. because the client was created at Height
+	Conflicting *tmclient.Header `json:"conflicting,omitempty" yaml:"conflicting,omitempty"`
+}
+
+func (m Misbehaviour) String() string {
+	return fmt.Sprintf("client %s on %s has a consensus state for %s at height %d that conflicts with the trusted header: root %X, validators %X, trusted root %X, validators %X",
+		m.ClientID, m.ChainID, m.TrackedChainID, m.Height, m.Stored.Root.GetHash(), m.Stored.ValidatorSetHash, m.Trusted.AppHash, m.Trusted.ValidatorsHash)
+}
+
+// Evidence returns the evidence of the misbehaviour to submit to the client, the
+// header it was updated with and the trusted header at the same height. It
+// returns false if the conflicting header is unknown.
+func (m Misbehaviour) Evidence() (tmclient.Evidence, bool) {
+	if m.Conflicting == nil {
+		return tmclient.Evidence{}, false
+	}
+	return tmclient.Evidence{
+		ClientID:         m.ClientID,
+		FromValidatorSet: m.Conflicting.ValidatorSet,
+		Header1:          *m.Conflicting,
+		Header2:          *m.Trusted,
+		ChainID:          m.TrackedChainID,
+	}, true
+}
+
+// MisbehaviourCheck is how far the consensus states stored by a client have been
+// checked for misbehaviour
+type MisbehaviourCheck struct {
+	// Height is the height of the tracked chain up to which consensus states
+	// have been checked
+	Height uint64 `json:"height"`
+
+	// ChainHeight is the height of the client's chain as of the last check that
+	// reached all its consensus states. A client is never updated to a height
+	// below its latest one, so those above Height were stored by later txs.
+	ChainHeight int64 `json:"chain-height"`
+}
+
+// CheckMisbehaviour compares the consensus states stored by the client on the
+// chain's path end, which tracks dst, since the check after with the headers
+// dst's lite client trusts at the same heights. It returns the conflicts found
+// and how far consensus states have been checked, which stops short of the first
+// conflict so it's found again until evidence of it freezes the client. Consensus
+// states older than the trusting period can't be checked and are skipped.
+func (c *Chain) CheckMisbehaviour(dst *Chain, after MisbehaviourCheck) ([]Misbehaviour, MisbehaviourCheck, error) {
+	if !c.PathSet() {
+		return nil, after, ErrPathNotSet
+	}
+
+	states, chainHeight, err := c.querySequenceSubspace(fmt.Sprintf("consensusState/%s/", c.PathEnd.ClientID), 0)
+	if err != nil {
+		return nil, after, err
+	}
+
+	var out []Misbehaviour
+	checked := after
+	advance := func(height uint64) {
+		if len(out) == 0 {
+			checked.Height = height
+		}
+	}
+	for _, height := range sortedSequences(states) {
+		if height <= after.Height {
+			continue
+		}
+
+		var cs clientExported.ConsensusState
+		if err = c.Cdc.UnmarshalBinaryLengthPrefixed(states[height], &cs); err != nil {
+			return out, checked, err
+		}
+
+		stored, ok := cs.(tmclient.ConsensusState)
+		if !ok {
+			advance(height)
+			continue
+		}
+
+		trusted, err := dst.TrustedHeaderAtHeight(int64(height))
+		var expired lite.ErrOldHeaderExpired
+		switch {
+		case errors.As(err, &expired):
+			advance(height)
+			continue
+		case err != nil:
+			return out, checked, err
+		}
+
+		if bytes.Equal(stored.Root.GetHash(), trusted.AppHash) && bytes.Equal(stored.ValidatorSetHash, trusted.ValidatorsHash) {
+			advance(height)
+			continue
+		}
+
+		// the update storing the consensus state came after the last complete check
+		conflicting, err := c.queryClientUpdateHeader(height, after.ChainHeight+1, chainHeight)
+		if err != nil {
+			return out, checked, err
+		}
+
+		out = append(out, Misbehaviour{
+			ChainID:        c.ChainID,
+			ClientID:       c.PathEnd.ClientID,
+			TrackedChainID: dst.ChainID,
+			Height:         height,
+			Stored:         stored,
+			Trusted:        trusted,
+			Conflicting:    conflicting,
+		})
+	}
+
+	// the updates storing conflicting consensus states must be searched again
+	if len(out) == 0 {
+		checked.ChainHeight = chainHeight
+	}
+	return out, checked, nil
+}
+
+// queryClientUpdateHeader returns the header the client on the chain's path end
+// was updated with at height, nil if there is none. Only the updates committed
+// between the chain heights minHeight and maxHeight are searched.
+func (c *Chain) queryClientUpdateHeader(height uint64, minHeight, maxHeight int64) (*tmclient.Header, error) {
+	res, err := c.QueryTxs([]string{fmt.Sprintf("%s.%s='%s'",
+		clientTypes.EventTypeUpdateClient, clientTypes.AttributeKeyClientID, c.PathEnd.ClientID)},
+		TxSearchOptions{MinHeight: minHeight, MaxHeight: maxHeight, SkipBlocks: true})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range res.Txs {
+		if tx.Code != 0 {
+			continue
+		}
+
+		for _, msg := range tx.Tx.GetMsgs() {
+			update, ok := msg.(clientTypes.MsgUpdateClient)
+			if !ok || update.ClientID != c.PathEnd.ClientID {
+				continue
+			}
+
+			if h, ok := update.Header.(tmclient.Header); ok && h.GetHeight() == height {
+				return &h, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// MisbehaviourChecker checks the clients on both ends of paths for misbehaviour of
+// the chains they track, remembering the heights it has already checked in its
+// progress store
+type MisbehaviourChecker struct {
+	progress *ProgressStore
+	checked  map[string]MisbehaviourCheck
+}
+
+// NewMisbehaviourChecker returns a MisbehaviourChecker resuming from the checks
+// recorded in progress, which may be nil to check every consensus state
+func NewMisbehaviourChecker(progress *ProgressStore) *MisbehaviourChecker {
+	return &MisbehaviourChecker{progress: progress, checked: make(map[string]MisbehaviourCheck)}
+}
+
+// Check checks the consensus states stored since the last check by the clients on
+// both ends of the paths and submits evidence of any misbehaviour found, which
+// freezes the client. Misbehaviour whose evidence couldn't be submitted is found
+// and submitted again on the next check. Frozen clients aren't checked. The
+// misbehaviour found is returned.
+func (m *MisbehaviourChecker) Check(c Chains, paths []Path) ([]Misbehaviour, error) {
+	var out []Misbehaviour
+	for _, path := range paths {
+		for _, p := range []Path{path, path.Reverse()} {
+			src, err := c.GetChain(p.Src.ChainID)
+			if err != nil {
+				return out, err
+			}
+
+			dst, err := c.GetChain(p.Dst.ChainID)
+			if err != nil {
+				return out, err
+			}
+
+			if err = src.setPath(&p.Src); err != nil {
+				return out, err
+			}
+
+			if err = dst.setPath(&p.Dst); err != nil {
+				return out, err
+			}
+
+			// once evidence has frozen the client it stores no more consensus states
+			cs, err := src.QueryClientState()
+			if err != nil {
+				src.logger.Error("failed to query client state", "client-id", src.PathEnd.ClientID, "err", err)
+				continue
+			} else if cs.ClientState != nil && cs.ClientState.IsFrozen() {
+				continue
+			}
+
+			key := fmt.Sprintf("%s/%s", src.ChainID, src.PathEnd.ClientID)
+			after, ok := m.checked[key]
+			if !ok {
+				if after, err = m.progress.MisbehaviourChecked(src.ChainID, src.PathEnd.ClientID); err != nil {
+					return out, err
+				}
+			}
+
+			found, checked, checkErr := src.CheckMisbehaviour(dst, after)
+			for _, mb := range found {
+				src.logger.Error("MISBEHAVIOUR", "client-id", mb.ClientID, "tracked-chain-id", mb.TrackedChainID,
+					"height", mb.Height, "misbehaviour", mb)
+				src.notifyOnce(fmt.Sprintf("misbehaviour/%s/%d", key, mb.Height), NotifyMisbehaviour, mb.String(),
+					"client-id", mb.ClientID, "tracked-chain-id", mb.TrackedChainID, "height", fmt.Sprint(mb.Height))
+				if err = src.submitMisbehaviour(mb); err != nil {
+					src.logger.Error("failed to submit evidence of misbehaviour", "client-id", mb.ClientID, "err", err)
+				}
+			}
+			out = append(out, found...)
+
+			// heights checked before the error are checked again next time
+			if checkErr != nil {
+				src.logger.Error("failed to check client for misbehaviour", "client-id", src.PathEnd.ClientID, "err", checkErr)
+				continue
+			}
+
+			m.checked[key] = checked
+			if err = m.progress.SetMisbehaviourChecked(src.ChainID, src.PathEnd.ClientID, checked); err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// submitMisbehaviour submits the evidence of mb to the client on the chain's path end
+func (c *Chain) submitMisbehaviour(mb Misbehaviour) error {
+	ev, ok := mb.Evidence()
+	if !ok {
+		return fmt.Errorf("no update of client %s at height %d to use as evidence", mb.ClientID, mb.Height)
+	}
+
+	res, err := c.SendMsg(c.SubmitMisbehaviour(ev))
+	if err != nil {
+		return err
+	} else if res.Code != 0 {
+		return fmt.Errorf("submit evidence tx %s failed: %s", res.TxHash, res.RawLog)
+	}
+
+	c.logger.Info("submitted evidence of misbehaviour", "client-id", mb.ClientID, "height", mb.Height, "tx-hash", res.TxHash)
+	return nil
+}
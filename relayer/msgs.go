@@ -0,0 +1,167 @@
+package relayer
+
+import (
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	clientTypes "github.com/cosmos/cosmos-sdk/x/ibc/02-client/types"
+	connTypes "github.com/cosmos/cosmos-sdk/x/ibc/03-connection/types"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+)
+
+// ClientMsgs are msgs destined for a chain whose proofs are verified by the
+// client ClientID on that chain, e.g. the msgs of one path
+type ClientMsgs struct {
+	ClientID string
+	Msgs     []sdk.Msg
+}
+
+// AssembleMsgs merges msgs destined for a single chain, from any number of steps,
+// packets or paths, so that its client updates come first and each client is
+// updated at most once, at the highest height. An update of a client at a lower
+// height is only kept if a msg whose proof that client verifies is from that
+// height, as proofs are verified against the consensus state stored at exactly
+// their height, and is ordered before the higher ones. The other msgs keep their
+// order.
+func AssembleMsgs(msgs ...ClientMsgs) []sdk.Msg {
+	type clientHeight struct {
+		clientID string
+		height   uint64
+	}
+
+	var all []sdk.Msg
+	proofHeights := make(map[clientHeight]bool)
+	highest := make(map[string]uint64)
+	for _, group := range msgs {
+		for _, msg := range group.Msgs {
+			if h, ok := msgProofHeight(msg); ok {
+				proofHeights[clientHeight{group.ClientID, h}] = true
+			}
+			if update, ok := msg.(clientTypes.MsgUpdateClient); ok && update.Header != nil {
+				if h := update.Header.GetHeight(); h > highest[update.ClientID] {
+					highest[update.ClientID] = h
+				}
+			}
+		}
+		all = append(all, group.Msgs...)
+	}
+
+	var updates, rest []sdk.Msg
+	seen := make(map[clientHeight]bool)
+	for _, msg := range all {
+		update, ok := msg.(clientTypes.MsgUpdateClient)
+		if !ok || update.Header == nil {
+			rest = append(rest, msg)
+			continue
+		}
+
+		key := clientHeight{update.ClientID, update.Header.GetHeight()}
+		if seen[key] || (key.height != highest[key.clientID] && !proofHeights[key]) {
+			continue
+		}
+		seen[key] = true
+		updates = append(updates, msg)
+	}
+
+	// a client can't be updated to a height below its latest one
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].(clientTypes.MsgUpdateClient).Header.GetHeight() < updates[j].(clientTypes.MsgUpdateClient).Header.GetHeight()
+	})
+
+	return append(updates, rest...)
+}
+
+// msgProofHeight returns the height of the proof carried by msg, if any
+func msgProofHeight(msg sdk.Msg) (uint64, bool) {
+	switch m := msg.(type) {
+	case connTypes.MsgConnectionOpenTry:
+		return m.ProofHeight, true
+	case connTypes.MsgConnectionOpenAck:
+		return m.ProofHeight, true
+	case connTypes.MsgConnectionOpenConfirm:
+		return m.ProofHeight, true
+	case chanTypes.MsgChannelOpenTry:
+		return m.ProofHeight, true
+	case chanTypes.MsgChannelOpenAck:
+		return m.ProofHeight, true
+	case chanTypes.MsgChannelOpenConfirm:
+		return m.ProofHeight, true
+	case chanTypes.MsgChannelCloseConfirm:
+		return m.ProofHeight, true
+	case chanTypes.MsgPacket:
+		return m.ProofHeight, true
+	case chanTypes.MsgAcknowledgement:
+		return m.ProofHeight, true
+	case chanTypes.MsgTimeout:
+		return m.ProofHeight, true
+	default:
+		return 0, false
+	}
+}
+
+// BatchMsgs splits msgs, as ordered by AssembleMsgs, into the txs needed to stay
+// within the chain's MaxMsgsPerTx and MaxTxBytes. Every tx starts with the client
+// updates: the first with all of them, the others with the latest update of each
+// client, as a client can't be updated to a height below its latest one. The
+// updates count towards MaxMsgsPerTx and MaxTxBytes, but every tx carries at
+// least one other msg. A msg larger than MaxTxBytes on its own is sent in a tx of
+// its own.
+func (c *Chain) BatchMsgs(msgs []sdk.Msg) [][]sdk.Msg {
+	n := 0
+	for n < len(msgs) {
+		if _, ok := msgs[n].(clientTypes.MsgUpdateClient); !ok {
+			break
+		}
+		n++
+	}
+	updates, rest := msgs[:n], msgs[n:]
+
+	switch {
+	case len(msgs) == 0:
+		return nil
+	case len(rest) == 0 || (c.MaxMsgsPerTx <= 0 && c.MaxTxBytes <= 0):
+		return [][]sdk.Msg{msgs}
+	}
+
+	var out [][]sdk.Msg
+	prefix := updates
+	batch, size := append([]sdk.Msg{}, prefix...), c.msgsSize(prefix)
+	for _, msg := range rest {
+		msgSize := c.msgsSize([]sdk.Msg{msg})
+		full := (c.MaxMsgsPerTx > 0 && len(batch) >= c.MaxMsgsPerTx) || (c.MaxTxBytes > 0 && size+msgSize > c.MaxTxBytes)
+		if full && len(batch) > len(prefix) {
+			out = append(out, batch)
+			prefix = latestUpdates(updates)
+			batch, size = append([]sdk.Msg{}, prefix...), c.msgsSize(prefix)
+		}
+		batch, size = append(batch, msg), size+msgSize
+	}
+
+	return append(out, batch)
+}
+
+// msgsSize returns the encoded size of msgs
+func (c *Chain) msgsSize(msgs []sdk.Msg) int {
+	size := 0
+	for _, msg := range msgs {
+		size += len(c.Cdc.MustMarshalBinaryBare(msg))
+	}
+	return size
+}
+
+// latestUpdates returns the last update of each client in updates, which are
+// ordered by height
+func latestUpdates(updates []sdk.Msg) []sdk.Msg {
+	last := make(map[string]int)
+	for i, msg := range updates {
+		last[msg.(clientTypes.MsgUpdateClient).ClientID] = i
+	}
+
+	var out []sdk.Msg
+	for i, msg := range updates {
+		if last[msg.(clientTypes.MsgUpdateClient).ClientID] == i {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package relayer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/ibc"
+	clientTypes "github.com/cosmos/cosmos-sdk/x/ibc/02-client/types"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	tmclient "github.com/cosmos/cosmos-sdk/x/ibc/07-tendermint"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+func testUpdate(clientID string, height int64) sdk.Msg {
+	return clientTypes.MsgUpdateClient{
+		ClientID: clientID,
+		Header:   tmclient.Header{SignedHeader: tmtypes.SignedHeader{Header: &tmtypes.Header{Height: height}}},
+	}
+}
+
+func testPacket(seq, proofHeight uint64) sdk.Msg {
+	return chanTypes.MsgPacket{Packet: chanTypes.Packet{Sequence: seq}, ProofHeight: proofHeight}
+}
+
+func TestAssembleMsgs(t *testing.T) {
+	cases := []struct {
+		name string
+		msgs []ClientMsgs
+		want []sdk.Msg
+	}{
+		{
+			"duplicate updates",
+			[]ClientMsgs{{"a", []sdk.Msg{testUpdate("a", 10), testPacket(1, 10), testUpdate("a", 10), testPacket(2, 10)}}},
+			[]sdk.Msg{testUpdate("a", 10), testPacket(1, 10), testPacket(2, 10)},
+		},
+		{
+			"lower height kept for a proof",
+			[]ClientMsgs{{"a", []sdk.Msg{testUpdate("a", 10), testPacket(1, 10), testUpdate("a", 5), testPacket(2, 5)}}},
+			[]sdk.Msg{testUpdate("a", 5), testUpdate("a", 10), testPacket(1, 10), testPacket(2, 5)},
+		},
+		{
+			"lower height without a proof dropped",
+			[]ClientMsgs{{"a", []sdk.Msg{testUpdate("a", 5), testUpdate("a", 10), testPacket(1, 10)}}},
+			[]sdk.Msg{testUpdate("a", 10), testPacket(1, 10)},
+		},
+		{
+			"updates first, other msgs keep their order",
+			[]ClientMsgs{
+				{"a", []sdk.Msg{testPacket(3, 10), testPacket(1, 10)}},
+				{"a", []sdk.Msg{testUpdate("a", 10), testPacket(2, 10)}},
+			},
+			[]sdk.Msg{testUpdate("a", 10), testPacket(3, 10), testPacket(1, 10), testPacket(2, 10)},
+		},
+		{
+			"updates from two clients",
+			[]ClientMsgs{
+				{"a", []sdk.Msg{testUpdate("a", 5), testUpdate("a", 8), testPacket(1, 8)}},
+				{"b", []sdk.Msg{testUpdate("b", 5), testPacket(2, 5), testUpdate("b", 9)}},
+			},
+			[]sdk.Msg{testUpdate("b", 5), testUpdate("a", 8), testUpdate("b", 9), testPacket(1, 8), testPacket(2, 5)},
+		},
+		{"no msgs", nil, nil},
+	}
+
+	for _, tc := range cases {
+		if got := AssembleMsgs(tc.msgs...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: AssembleMsgs() = %v, want %v", tc.name, msgTypes(got), msgTypes(tc.want))
+		}
+	}
+}
+
+func TestBatchMsgs(t *testing.T) {
+	cdc := codec.New()
+	sdk.RegisterCodec(cdc)
+	codec.RegisterCrypto(cdc)
+	ibc.AppModuleBasic{}.RegisterCodec(cdc)
+
+	c := &Chain{Cdc: cdc}
+	size := func(msgs ...sdk.Msg) int { return c.msgsSize(msgs) }
+
+	ua5, ua10, ub7 := testUpdate("a", 5), testUpdate("a", 10), testUpdate("b", 7)
+	p1, p2, p3 := testPacket(1, 10), testPacket(2, 5), testPacket(3, 7)
+
+	cases := []struct {
+		name     string
+		maxMsgs  int
+		maxBytes int
+		msgs     []sdk.Msg
+		want     [][]sdk.Msg
+	}{
+		{"no msgs", 2, 0, nil, nil},
+		{"no limits", 0, 0, []sdk.Msg{ua10, p1, p2, p3}, [][]sdk.Msg{{ua10, p1, p2, p3}}},
+		{"only updates", 1, 0, []sdk.Msg{ua5, ua10}, [][]sdk.Msg{{ua5, ua10}}},
+		{
+			"msg count limit",
+			2, 0,
+			[]sdk.Msg{p1, p2, p3},
+			[][]sdk.Msg{{p1, p2}, {p3}},
+		},
+		{
+			"later txs start with the latest update of each client",
+			3, 0,
+			[]sdk.Msg{ua5, ua10, ub7, p1, p2, p3},
+			[][]sdk.Msg{{ua5, ua10, ub7, p1}, {ua10, ub7, p2}, {ua10, ub7, p3}},
+		},
+		{
+			"byte limit",
+			0, size(ua10, p1, p2),
+			[]sdk.Msg{ua10, p1, p2, p3},
+			[][]sdk.Msg{{ua10, p1, p2}, {ua10, p3}},
+		},
+		{
+			"msg larger than the byte limit on its own",
+			0, size(p1) - 1,
+			[]sdk.Msg{ua10, p1, p2},
+			[][]sdk.Msg{{ua10, p1}, {ua10, p2}},
+		},
+	}
+
+	for _, tc := range cases {
+		c.MaxMsgsPerTx, c.MaxTxBytes = tc.maxMsgs, tc.maxBytes
+		if got := c.BatchMsgs(tc.msgs); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: BatchMsgs() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package relayer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// Types of Notification
+const (
+	NotifyConnectionOpen = "connection_open"
+	NotifyChannelOpen    = "channel_open"
+	NotifyPacketTimeout  = "packet_timeout"
+	NotifyClientExpiring = "client_expiring"
+	NotifyClientExpired  = "client_expired"
+	NotifyTxFailing      = "tx_failing"
+	NotifyMisbehaviour   = "misbehaviour"
+	NotifyChainHalted    = "chain_halted"
+)
+
+// notificationQueueSize is how many notifications may wait for a sink before
+// further ones are dropped
+const notificationQueueSize = 100
+
+// Notification is an event of the relayer worth telling its operators about
+type Notification struct {
+	Type       string            `json:"type"`
+	Time       time.Time         `json:"time"`
+	ChainID    string            `json:"chain-id"`
+	Path       string            `json:"path,omitempty"`
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// NotificationSink delivers notifications somewhere
+type NotificationSink interface {
+	Send(n Notification) error
+	Name() string
+}
+
+// Notifier sends notifications to its sinks in the background, so relaying isn't
+// held up by slow sinks. A nil Notifier drops all notifications.
+type Notifier struct {
+	// TxFailureThreshold is how many txs in a row must fail on a chain before
+	// a NotifyTxFailing notification is sent
+	TxFailureThreshold int
+
+	queues []chan Notification
+	wg     sync.WaitGroup
+	logger log.Logger
+
+	mtx    sync.Mutex
+	once   map[string]bool
+	closed bool
+}
+
+// NewNotifier returns a Notifier sending to sinks, failures are logged to logger
+func NewNotifier(logger log.Logger, txFailureThreshold int, sinks ...NotificationSink) *Notifier {
+	n := &Notifier{TxFailureThreshold: txFailureThreshold, logger: logger, once: make(map[string]bool)}
+	for _, sink := range sinks {
+		queue := make(chan Notification, notificationQueueSize)
+		n.queues = append(n.queues, queue)
+		n.wg.Add(1)
+		go n.run(sink, queue)
+	}
+	return n
+}
+
+func (n *Notifier) run(sink NotificationSink, queue <-chan Notification) {
+	defer n.wg.Done()
+	for notification := range queue {
+		if err := sink.Send(notification); err != nil {
+			n.logger.Error("failed to send notification", "sink", sink.Name(), "type", notification.Type, "err", err)
+		}
+	}
+}
+
+// Notify queues notification for every sink, dropping it for sinks whose queue is full
+func (n *Notifier) Notify(notification Notification) {
+	if n == nil {
+		return
+	}
+
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.closed {
+		return
+	}
+	for _, queue := range n.queues {
+		select {
+		case queue <- notification:
+		default:
+			n.logger.Error("notification queue full, dropping notification", "type", notification.Type)
+		}
+	}
+}
+
+// NotifyOnce calls Notify unless a notification with the same key has already
+// been sent, for conditions that are checked repeatedly until resolved
+func (n *Notifier) NotifyOnce(key string, notification Notification) {
+	if n == nil {
+		return
+	}
+
+	n.mtx.Lock()
+	sent := n.once[key]
+	n.once[key] = true
+	n.mtx.Unlock()
+
+	if !sent {
+		n.Notify(notification)
+	}
+}
+
+// Resolve forgets key, so the next NotifyOnce with it is sent again. It is called
+// once the condition reported under key no longer holds.
+func (n *Notifier) Resolve(key string) {
+	if n == nil {
+		return
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	delete(n.once, key)
+}
+
+// Close waits for the queued notifications to be sent, no more may be sent after
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+
+	n.mtx.Lock()
+	if !n.closed {
+		n.closed = true
+		for _, queue := range n.queues {
+			close(queue)
+		}
+	}
+	n.mtx.Unlock()
+	n.wg.Wait()
+}
+
+// WebhookSink POSTs every notification as JSON to a URL, retrying with backoff
+// when the request fails or isn't answered with a 2xx status
+type WebhookSink struct {
+	URL     string
+	Retries int
+
+	client *http.Client
+}
+
+// NewWebhookSink returns a WebhookSink whose requests time out after timeout
+func NewWebhookSink(url string, retries int, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{URL: url, Retries: retries, client: &http.Client{Timeout: timeout}}
+}
+
+// Name implements NotificationSink
+func (s *WebhookSink) Name() string {
+	return s.URL
+}
+
+// Send implements NotificationSink
+func (s *WebhookSink) Send(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err = s.post(body)
+		if err == nil || attempt >= s.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *WebhookSink) post(body []byte) error {
+	res, err := s.client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// EventFileSink appends every notification to a file as a line of JSON
+type EventFileSink struct {
+	file *os.File
+}
+
+// NewEventFileSink returns an EventFileSink appending to the file at path
+func NewEventFileSink(path string) (*EventFileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	return &EventFileSink{file: file}, nil
+}
+
+// Name implements NotificationSink
+func (s *EventFileSink) Name() string {
+	return s.file.Name()
+}
+
+// Send implements NotificationSink
+func (s *EventFileSink) Send(n Notification) error {
+	bz, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(bz, '\n'))
+	return err
+}
+
+// SetNotifier sets the notifier sent notifications about the chain, it must be
+// called before the chain is used by other goroutines
+func (c *Chain) SetNotifier(n *Notifier) {
+	c.notifier = n
+}
+
+// notify sends a notification about the chain, see Notifier.Notify
+func (c *Chain) notify(typ, message string, attrs ...string) {
+	c.notifier.Notify(c.notification(typ, message, attrs...))
+}
+
+// notifyOnce sends a notification about the chain once per key, see Notifier.NotifyOnce
+func (c *Chain) notifyOnce(key, typ, message string, attrs ...string) {
+	c.notifier.NotifyOnce(key, c.notification(typ, message, attrs...))
+}
+
+// notification returns a notification about the chain, on its path if set, with
+// attrs as alternating keys and values
+func (c *Chain) notification(typ, message string, attrs ...string) Notification {
+	n := Notification{Type: typ, ChainID: c.ChainID, Message: message}
+	if c.PathEnd != nil {
+		n.Path = c.PathEnd.String()
+	}
+	if len(attrs) > 0 {
+		n.Attributes = make(map[string]string, len(attrs)/2)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			n.Attributes[attrs[i]] = attrs[i+1]
+		}
+	}
+	return n
+}
+
+// notifyTimeouts notifies about the packets timed out on the chain by msgs
+func (c *Chain) notifyTimeouts(msgs []sdk.Msg) {
+	for _, msg := range msgs {
+		if m, ok := msg.(chanTypes.MsgTimeout); ok {
+			c.notify(NotifyPacketTimeout, fmt.Sprintf("packet %d sent on %s timed out", m.Packet.Sequence, c.ChainID),
+				"sequence", fmt.Sprint(m.Packet.Sequence), "channel-id", m.Packet.SourceChannel,
+				"counterparty-channel-id", m.Packet.DestinationChannel)
+		}
+	}
+}
+
+// recordTxResult counts the txs in a row that failed on the chain and notifies
+// once the Notifier's TxFailureThreshold is reached. Txs not sent while the chain
+// is paused for lack of funds aren't counted.
+func (c *Chain) recordTxResult(msgs []sdk.Msg, res sdk.TxResponse, err error) {
+	if errors.Is(err, ErrInsufficientBalance) {
+		return
+	}
+
+	c.txFailuresMtx.Lock()
+	if err == nil && res.Code == 0 {
+		c.txFailures = 0
+	} else {
+		c.txFailures++
+	}
+	failures := c.txFailures
+	c.txFailuresMtx.Unlock()
+
+	if c.notifier == nil || failures != c.notifier.TxFailureThreshold {
+		return
+	}
+
+	reason := res.RawLog
+	if err != nil {
+		reason = err.Error()
+	}
+	c.notify(NotifyTxFailing, fmt.Sprintf("%d txs in a row failed on %s", failures, c.ChainID),
+		"msgs", msgTypes(msgs), "tx-hash", res.TxHash, "error", reason)
+}
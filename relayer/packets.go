@@ -0,0 +1,419 @@
+package relayer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	chanState "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/exported"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	xferKeeper "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/keeper"
+	xferTypes "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/types"
+	ibctypes "github.com/cosmos/cosmos-sdk/x/ibc/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+// UnrelayedSequences lists what is stuck on one direction of a path: packets
+// committed on src that have not been received on dst, and acknowledgements
+// written on dst that have not been processed on src
+type UnrelayedSequences struct {
+	Path      string            `json:"path" yaml:"path"`
+	SrcHeight int64             `json:"src-height" yaml:"src-height"`
+	DstHeight int64             `json:"dst-height" yaml:"dst-height"`
+	Packets   []UnrelayedPacket `json:"packets" yaml:"packets"`
+	Acks      []UnrelayedAck    `json:"acks" yaml:"acks"`
+}
+
+// UnrelayedPacket is a packet sent on src that has not been received on dst. The
+// heights are unknown (zero) if the tx that sent the packet could not be found.
+type UnrelayedPacket struct {
+	Sequence      uint64 `json:"sequence" yaml:"sequence"`
+	SendHeight    int64  `json:"send-height" yaml:"send-height"`
+	TimeoutHeight uint64 `json:"timeout-height" yaml:"timeout-height"`
+	TimedOut      bool   `json:"timed-out" yaml:"timed-out"`
+}
+
+// UnrelayedAck is an acknowledgement written on dst for a packet whose commitment
+// is still on src. RecvHeight is zero if the tx that received the packet could not be found.
+type UnrelayedAck struct {
+	Sequence   uint64 `json:"sequence" yaml:"sequence"`
+	RecvHeight int64  `json:"recv-height" yaml:"recv-height"`
+}
+
+// SentPacket is a packet along with the height of the tx that sent it
+type SentPacket struct {
+	Packet chanTypes.Packet `json:"packet" yaml:"packet"`
+	Height int64            `json:"height" yaml:"height"`
+}
+
+// QueryUnrelayed returns the packets and acknowledgements on the path between src
+// and dst that are waiting to be relayed from src to dst and back.
+// A packet has been received once the destination has written its acknowledgement,
+// which ICS20 does for every packet it receives.
+func QueryUnrelayed(src, dst *Chain) (*UnrelayedSequences, error) {
+	if !PathsSet(src, dst) {
+		return nil, ErrPathNotSet
+	}
+
+	commitments, srcHeight, err := src.QueryPacketCommitments(0)
+	if err != nil {
+		return nil, err
+	}
+
+	acks, dstHeight, err := dst.QueryPacketAcknowledgements(0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &UnrelayedSequences{
+		Path:      Path{Src: *src.PathEnd, Dst: *dst.PathEnd}.String(),
+		SrcHeight: srcHeight,
+		DstHeight: dstHeight,
+		Packets:   []UnrelayedPacket{},
+		Acks:      []UnrelayedAck{},
+	}
+
+	pending := make(map[uint64][]byte)
+	for _, seq := range sortedSequences(commitments) {
+		if _, ok := acks[seq]; ok {
+			out.Acks = append(out.Acks, UnrelayedAck{Sequence: seq})
+			continue
+		}
+		out.Packets = append(out.Packets, UnrelayedPacket{Sequence: seq})
+		pending[seq] = commitments[seq]
+	}
+
+	if len(out.Packets) > 0 {
+		sent, err := src.QuerySentPackets(dst.PathEnd, pending, TxSearchOptions{MaxHeight: srcHeight, SkipBlocks: true})
+		if err != nil {
+			return nil, err
+		}
+
+		for i, p := range out.Packets {
+			if s, ok := sent[p.Sequence]; ok {
+				out.Packets[i].SendHeight = s.Height
+				out.Packets[i].TimeoutHeight = s.Packet.GetTimeoutHeight()
+				out.Packets[i].TimedOut = uint64(dstHeight) >= s.Packet.GetTimeoutHeight()
+			}
+		}
+	}
+
+	if len(out.Acks) > 0 {
+		received, err := dst.QueryReceivedPackets(TxSearchOptions{MaxHeight: dstHeight, SkipBlocks: true})
+		if err != nil {
+			return nil, err
+		}
+
+		for i, a := range out.Acks {
+			out.Acks[i].RecvHeight = received[a.Sequence]
+		}
+	}
+
+	return out, nil
+}
+
+// RelayPacketMsgs returns the msgs that deliver the packet with the given sequence,
+// sent on src, to dst along with the client update needed to prove it
+func RelayPacketMsgs(src, dst *Chain, seq uint64) (*RelayMsgs, error) {
+	hs, err := UpdatesWithHeaders(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	commitment, sent, err := src.querySentPacket(dst, seq, hs[src.ChainID].Height)
+	if err != nil {
+		return nil, err
+	}
+
+	ack, err := dst.QueryPacketAcknowledgement(hs[dst.ChainID].Height, seq)
+	if err != nil {
+		return nil, err
+	} else if len(ack.Data) > 0 {
+		return nil, fmt.Errorf("packet %d has already been received on %s", seq, dst.ChainID)
+	}
+
+	return &RelayMsgs{
+		Src: []sdk.Msg{},
+		Dst: []sdk.Msg{
+			dst.UpdateClient(hs[src.ChainID]),
+			dst.RecvPacket(sent.Packet, commitment),
+		},
+	}, nil
+}
+
+// RelayAckMsgs returns the msgs that deliver the acknowledgement dst wrote for the
+// packet with the given sequence, sent on src, back to src along with the client
+// update needed to prove it
+func RelayAckMsgs(src, dst *Chain, seq uint64) (*RelayMsgs, error) {
+	hs, err := UpdatesWithHeaders(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	_, sent, err := src.querySentPacket(dst, seq, hs[src.ChainID].Height)
+	if err != nil {
+		return nil, err
+	}
+
+	ack, err := dst.QueryPacketAcknowledgement(hs[dst.ChainID].Height, seq)
+	if err != nil {
+		return nil, err
+	} else if len(ack.Data) == 0 {
+		return nil, fmt.Errorf("packet %d has not been acknowledged on %s", seq, dst.ChainID)
+	}
+
+	// ICS20 acknowledges every packet it receives with an empty AckDataTransfer
+	return &RelayMsgs{
+		Src: []sdk.Msg{
+			src.UpdateClient(hs[dst.ChainID]),
+			src.AckPacket(sent.Packet, xferTypes.AckDataTransfer{}, ack),
+		},
+		Dst: []sdk.Msg{},
+	}, nil
+}
+
+// TimeoutPacketMsgs returns the msgs that time out the packet with the given
+// sequence, sent on src, once dst has passed its timeout height without receiving
+// it, along with the client update needed to prove it
+func TimeoutPacketMsgs(src, dst *Chain, seq uint64) (*RelayMsgs, error) {
+	hs, err := UpdatesWithHeaders(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	srcHeight, dstHeight := hs[src.ChainID].Height, hs[dst.ChainID].Height
+	_, sent, err := src.querySentPacket(dst, seq, srcHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout := sent.Packet.GetTimeoutHeight(); uint64(dstHeight) < timeout {
+		return nil, fmt.Errorf("packet %d times out at height %d, %s is at height %d", seq, timeout, dst.ChainID, dstHeight)
+	}
+
+	channel, err := src.QueryChannel(srcHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg sdk.Msg
+	switch channel.Channel.GetOrdering() {
+	case chanState.ORDERED:
+		recv, err := dst.QueryNextSequenceRecv(dstHeight)
+		if err != nil {
+			return nil, err
+		} else if recv.NextSequenceRecv > seq {
+			return nil, fmt.Errorf("packet %d has already been received on %s", seq, dst.ChainID)
+		}
+		msg = src.TimeoutPacket(sent.Packet, recv.NextSequenceRecv, recv.Proof, recv.ProofHeight)
+	default:
+		ack, err := dst.QueryPacketAcknowledgement(dstHeight, seq)
+		if err != nil {
+			return nil, err
+		} else if len(ack.Data) > 0 {
+			return nil, fmt.Errorf("packet %d has already been received on %s", seq, dst.ChainID)
+		}
+		msg = src.TimeoutPacket(sent.Packet, 0, ack.Proof, ack.ProofHeight)
+	}
+
+	return &RelayMsgs{
+		Src: []sdk.Msg{src.UpdateClient(hs[dst.ChainID]), msg},
+		Dst: []sdk.Msg{},
+	}, nil
+}
+
+// querySentPacket returns the commitment to the packet with the given sequence
+// sent on the chain's path end to dst, along with the packet itself
+func (c *Chain) querySentPacket(dst *Chain, seq uint64, height int64) (CommitmentResponse, SentPacket, error) {
+	commitment, err := c.QueryPacketCommitment(height, seq)
+	if err != nil {
+		return CommitmentResponse{}, SentPacket{}, err
+	} else if len(commitment.Data) == 0 {
+		return CommitmentResponse{}, SentPacket{}, fmt.Errorf("no commitment to packet %d on %s, it was never sent or has been acknowledged or timed out", seq, c.ChainID)
+	}
+
+	sent, err := c.QuerySentPackets(dst.PathEnd, map[uint64][]byte{seq: commitment.Data}, TxSearchOptions{MaxHeight: height, SkipBlocks: true})
+	if err != nil {
+		return CommitmentResponse{}, SentPacket{}, err
+	}
+
+	packet, ok := sent[seq]
+	if !ok {
+		return CommitmentResponse{}, SentPacket{}, fmt.Errorf("couldn't find the tx that sent packet %d on %s", seq, c.ChainID)
+	}
+
+	return commitment, packet, nil
+}
+
+// QueryTransferSequence returns the sequence of the packet that msg, committed on
+// the chain at height, sent over the chain's path end to dst
+func (c *Chain) QueryTransferSequence(dst *Chain, msg xferTypes.MsgTransfer, height int64) (uint64, error) {
+	commitments, _, err := c.QueryPacketCommitments(height)
+	if err != nil {
+		return 0, err
+	}
+
+	commitment := chanTypes.CommitPacket(transferPacketData(msg, dst.PathEnd, height))
+	for seq, bz := range commitments {
+		if bytes.Equal(bz, commitment) {
+			return seq, nil
+		}
+	}
+	return 0, fmt.Errorf("no commitment to the packet sent at height %d on %s", height, c.ChainID)
+}
+
+// QueryPacketCommitments returns the packet commitments stored for the chain's
+// path end by sequence, along with the height they were queried at
+func (c *Chain) QueryPacketCommitments(height int64) (map[uint64][]byte, int64, error) {
+	if !c.PathSet() {
+		return nil, 0, ErrPathNotSet
+	}
+
+	prefix := strings.TrimSuffix(ibctypes.PacketCommitmentPath(c.PathEnd.PortID, c.PathEnd.ChannelID, 0), "0")
+	return c.querySequenceSubspace(prefix, height)
+}
+
+// QueryPacketAcknowledgements returns the packet acknowledgements stored for the
+// chain's path end by sequence, along with the height they were queried at
+func (c *Chain) QueryPacketAcknowledgements(height int64) (map[uint64][]byte, int64, error) {
+	if !c.PathSet() {
+		return nil, 0, ErrPathNotSet
+	}
+
+	prefix := strings.TrimSuffix(ibctypes.PacketAcknowledgementPath(c.PathEnd.PortID, c.PathEnd.ChannelID, 0), "0")
+	return c.querySequenceSubspace(prefix, height)
+}
+
+// querySequenceSubspace returns all values in the IBC store under prefix, keyed
+// by the sequence that ends their key
+func (c *Chain) querySequenceSubspace(prefix string, height int64) (map[uint64][]byte, int64, error) {
+	res, err := c.QueryABCI(abci.RequestQuery{
+		Path:   "store/ibc/subspace",
+		Data:   []byte(prefix),
+		Height: height,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var kvs []sdk.KVPair
+	if len(res.Value) > 0 {
+		if err = c.Cdc.UnmarshalBinaryLengthPrefixed(res.Value, &kvs); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	out := make(map[uint64][]byte, len(kvs))
+	for _, kv := range kvs {
+		key := string(kv.Key)
+		seq, err := strconv.ParseUint(key[strings.LastIndex(key, "/")+1:], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("malformed packet key %s: %w", key, err)
+		}
+		out[seq] = kv.Value
+	}
+
+	return out, res.Height, nil
+}
+
+// QuerySentPackets returns the ICS20 packets sent over the chain's path end to
+// counterparty whose commitments are given, by sequence. Packets don't appear in
+// events yet, so they are rebuilt from the MsgTransfers that sent them and matched
+// to the commitments by hash.
+func (c *Chain) QuerySentPackets(counterparty *PathEnd, commitments map[uint64][]byte, opts TxSearchOptions) (map[uint64]SentPacket, error) {
+	if !c.PathSet() {
+		return nil, ErrPathNotSet
+	}
+
+	seqs := make(map[string]uint64, len(commitments))
+	for seq, commitment := range commitments {
+		seqs[string(commitment)] = seq
+	}
+
+	res, err := c.QueryTxs([]string{fmt.Sprintf("message.action='%s'", xferTypes.MsgTransfer{}.Type())}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint64]SentPacket)
+	for _, tx := range res.Txs {
+		if tx.Code != 0 {
+			continue
+		}
+
+		for _, msg := range tx.Tx.GetMsgs() {
+			transfer, ok := msg.(xferTypes.MsgTransfer)
+			if !ok || transfer.SourcePort != c.PathEnd.PortID || transfer.SourceChannel != c.PathEnd.ChannelID {
+				continue
+			}
+
+			data := transferPacketData(transfer, counterparty, tx.Height)
+			seq, ok := seqs[string(chanTypes.CommitPacket(data))]
+			if !ok {
+				continue
+			}
+
+			packet := chanTypes.NewPacket(data, seq, c.PathEnd.PortID, c.PathEnd.ChannelID, counterparty.PortID, counterparty.ChannelID)
+			out[seq] = SentPacket{Packet: packet, Height: tx.Height}
+		}
+	}
+
+	return out, nil
+}
+
+// QueryReceivedPackets returns the heights at which ICS20 packets were received
+// on the chain's path end, by sequence
+func (c *Chain) QueryReceivedPackets(opts TxSearchOptions) (map[uint64]int64, error) {
+	if !c.PathSet() {
+		return nil, ErrPathNotSet
+	}
+
+	res, err := c.QueryTxs([]string{fmt.Sprintf("message.action='%s'", xferTypes.PacketDataTransfer{}.Type())}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint64]int64)
+	for _, tx := range res.Txs {
+		if tx.Code != 0 {
+			continue
+		}
+
+		for _, msg := range tx.Tx.GetMsgs() {
+			recv, ok := msg.(chanTypes.MsgPacket)
+			if !ok || recv.DestinationPort != c.PathEnd.PortID || recv.DestinationChannel != c.PathEnd.ChannelID {
+				continue
+			}
+			out[recv.Sequence] = tx.Height
+		}
+	}
+
+	return out, nil
+}
+
+// transferPacketData rebuilds the packet data the transfer module created for msg
+// in a tx at height, mirroring its SendTransfer
+func transferPacketData(msg xferTypes.MsgTransfer, counterparty *PathEnd, height int64) xferTypes.PacketDataTransfer {
+	amount := msg.Amount
+	if msg.Source {
+		prefix := xferTypes.GetDenomPrefix(counterparty.PortID, counterparty.ChannelID)
+		amount = make(sdk.Coins, len(msg.Amount))
+		for i, coin := range msg.Amount {
+			amount[i] = sdk.NewCoin(prefix+coin.Denom, coin.Amount)
+		}
+	}
+
+	return xferTypes.NewPacketDataTransfer(amount, msg.Sender, msg.Receiver, msg.Source, uint64(height)+xferKeeper.DefaultPacketTimeout)
+}
+
+func sortedSequences(m map[uint64][]byte) []uint64 {
+	seqs := make([]uint64, 0, len(m))
+	for seq := range m {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs
+}
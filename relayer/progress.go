@@ -0,0 +1,315 @@
+package relayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	xferTypes "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// ProgressStore records how far the relayer got on each path so it can resume
+// after a restart instead of scanning every chain from the start. Progress is
+// kept per direction of a path: Path{Src: a, Dst: b} holds the progress of
+// relaying packets sent on a to b. A nil ProgressStore records nothing.
+type ProgressStore struct {
+	mtx sync.Mutex
+	db  dbm.DB
+}
+
+// PathProgress is the relay progress of one direction of a path
+type PathProgress struct {
+	Path       string   `json:"path" yaml:"path"`
+	LastHeight int64    `json:"last-height" yaml:"last-height"`
+	Relayed    []uint64 `json:"relayed-sequences" yaml:"relayed-sequences"`
+}
+
+// OpenProgressStore opens the progress database in the relayer home, creating it if needed
+func OpenProgressStore(home string) (*ProgressStore, error) {
+	db, err := dbm.NewGoLevelDB("progress", home)
+	if err != nil {
+		return nil, fmt.Errorf("can't open relay progress database, which start holds while it runs: %w", err)
+	}
+	return &ProgressStore{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *ProgressStore) Close() error {
+	return s.db.Close()
+}
+
+// LastHeight returns the last height scanned on the source chain of the path, 0 if none
+func (s *ProgressStore) LastHeight(p Path) (int64, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	bz, err := s.db.Get(progressHeightKey(p))
+	if err != nil || bz == nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bz), 10, 64)
+}
+
+// SetLastHeight records height as scanned on the source chain of the path. Heights
+// never go backwards, a height lower than the recorded one is ignored.
+func (s *ProgressStore) SetLastHeight(p Path, height int64) error {
+	if s == nil || height <= 0 {
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	key := progressHeightKey(p)
+	bz, err := s.db.Get(key)
+	if err != nil {
+		return err
+	}
+	if bz != nil {
+		last, err := strconv.ParseInt(string(bz), 10, 64)
+		if err == nil && last >= height {
+			return nil
+		}
+	}
+	return s.db.SetSync(key, []byte(strconv.FormatInt(height, 10)))
+}
+
+// Relayed returns true if the packet with sequence seq sent on the source chain of
+// the path has been delivered to its destination
+func (s *ProgressStore) Relayed(p Path, seq uint64) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.db.Has(progressSequenceKey(p, seq))
+}
+
+// SetRelayed records the packets with the given sequences as delivered
+func (s *ProgressStore) SetRelayed(p Path, seqs ...uint64) error {
+	if s == nil || len(seqs) == 0 {
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, seq := range seqs {
+		batch.Set(progressSequenceKey(p, seq), []byte{1})
+	}
+	return batch.WriteSync()
+}
+
+// Progress returns the progress of both directions of the path
+func (s *ProgressStore) Progress(p Path) ([]PathProgress, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	var out []PathProgress
+	for _, dir := range []Path{p, p.Reverse()} {
+		height, err := s.LastHeight(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		seqs, err := s.relayedSequences(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, PathProgress{Path: dir.String(), LastHeight: height, Relayed: seqs})
+	}
+	return out, nil
+}
+
+// Reset forgets all progress on both directions of the path
+func (s *ProgressStore) Reset(p Path) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, dir := range []Path{p, p.Reverse()} {
+		batch.Delete(progressHeightKey(dir))
+
+		keys, err := s.keys(progressSequenceKey(dir, 0), progressSequenceKey(dir, math.MaxUint64))
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			batch.Delete(key)
+		}
+	}
+	return batch.WriteSync()
+}
+
+func (s *ProgressStore) relayedSequences(p Path) ([]uint64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	keys, err := s.keys(progressSequenceKey(p, 0), progressSequenceKey(p, math.MaxUint64))
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for _, key := range keys {
+		k := string(key)
+		seq, err := strconv.ParseUint(k[strings.LastIndex(k, "/")+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed relay progress key %s: %w", k, err)
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs, nil
+}
+
+// CONTRACT: s.mtx must be held
+func (s *ProgressStore) keys(start, end []byte) ([][]byte, error) {
+	itr, err := s.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	var keys [][]byte
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, append([]byte{}, itr.Key()...))
+	}
+	return keys, nil
+}
+
+// MisbehaviourChecked returns how far the consensus states of the client on the
+// chain have been checked for misbehaviour, nothing if they never have
+func (s *ProgressStore) MisbehaviourChecked(chainID, clientID string) (MisbehaviourCheck, error) {
+	var out MisbehaviourCheck
+	if s == nil {
+		return out, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	bz, err := s.db.Get(misbehaviourKey(chainID, clientID))
+	if err != nil || bz == nil {
+		return out, err
+	}
+	if err = json.Unmarshal(bz, &out); err != nil {
+		return out, fmt.Errorf("malformed misbehaviour check of %s on %s: %w", clientID, chainID, err)
+	}
+	return out, nil
+}
+
+// SetMisbehaviourChecked records how far the consensus states of the client on
+// the chain have been checked for misbehaviour
+func (s *ProgressStore) SetMisbehaviourChecked(chainID, clientID string, checked MisbehaviourCheck) error {
+	if s == nil {
+		return nil
+	}
+
+	bz, err := json.Marshal(checked)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.db.SetSync(misbehaviourKey(chainID, clientID), bz)
+}
+
+// progressPathKey identifies a direction of a path by the identifiers of its ends.
+// ICS24 identifiers can't contain "/".
+func progressPathKey(p Path) string {
+	end := func(e PathEnd) string {
+		return strings.Join([]string{e.ChainID, e.ClientID, e.ConnectionID, e.ChannelID, e.PortID}, "/")
+	}
+	return end(p.Src) + "/" + end(p.Dst)
+}
+
+func progressHeightKey(p Path) []byte {
+	return []byte(fmt.Sprintf("height/%s", progressPathKey(p)))
+}
+
+func progressSequenceKey(p Path, seq uint64) []byte {
+	return []byte(fmt.Sprintf("seq/%s/%020d", progressPathKey(p), seq))
+}
+
+func misbehaviourKey(chainID, clientID string) []byte {
+	return []byte(fmt.Sprintf("misbehaviour/%s/%s", chainID, clientID))
+}
+
+// recordProgress records the progress made by msgs, it must only be called once
+// they have been committed on both chains
+func recordProgress(progress *ProgressStore, path Path, msgs *RelayMsgs) error {
+	if err := progress.SetLastHeight(path, msgs.SrcScanned); err != nil {
+		return err
+	}
+	if err := progress.SetLastHeight(path.Reverse(), msgs.DstScanned); err != nil {
+		return err
+	}
+	if err := progress.SetRelayed(path, packetSequences(msgs.Dst)...); err != nil {
+		return err
+	}
+	return progress.SetRelayed(path.Reverse(), packetSequences(msgs.Src)...)
+}
+
+// unrelayed drops the packets of msgs that were already delivered on the path
+func unrelayed(progress *ProgressStore, p Path, msgs []sdk.Msg) ([]sdk.Msg, error) {
+	var out []sdk.Msg
+	for _, msg := range msgs {
+		if seq, ok := packetSequence(msg); ok {
+			relayed, err := progress.Relayed(p, seq)
+			if err != nil {
+				return nil, err
+			} else if relayed {
+				continue
+			}
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+// packetSequences returns the sequences of the packets delivered by msgs
+func packetSequences(msgs []sdk.Msg) []uint64 {
+	var seqs []uint64
+	for _, msg := range msgs {
+		if seq, ok := packetSequence(msg); ok {
+			seqs = append(seqs, seq)
+		}
+	}
+	return seqs
+}
+
+// packetSequence returns the sequence of the packet delivered by msg, false if it
+// delivers none
+func packetSequence(msg sdk.Msg) (uint64, bool) {
+	switch m := msg.(type) {
+	case chanTypes.MsgPacket:
+		return m.Packet.Sequence, true
+	case xferTypes.MsgRecvPacket:
+		if m.Packet != nil {
+			return m.Packet.GetSequence(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package relayer
+
+import (
+	"reflect"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestProgressRelayedSequences(t *testing.T) {
+	progress := &ProgressStore{db: dbm.NewMemDB()}
+	p := Path{
+		Src: PathEnd{ChainID: "a", ClientID: "clienta", ConnectionID: "conna", ChannelID: "chana", PortID: "transfer"},
+		Dst: PathEnd{ChainID: "b", ClientID: "clientb", ConnectionID: "connb", ChannelID: "chanb", PortID: "transfer"},
+	}
+
+	if err := progress.SetLastHeight(p, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress.SetRelayed(p, 3, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress.SetRelayed(p.Reverse(), 7); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := progress.Progress(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PathProgress{
+		{Path: p.String(), LastHeight: 10, Relayed: []uint64{1, 2, 3}},
+		{Path: p.Reverse().String(), Relayed: []uint64{7}},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Fatalf("Progress() = %+v, want %+v", state, want)
+	}
+
+	packet := func(seq uint64) sdk.Msg {
+		return chanTypes.MsgPacket{Packet: chanTypes.Packet{Sequence: seq}}
+	}
+	msgs, err := unrelayed(progress, p, []sdk.Msg{packet(2), packet(4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(msgs, []sdk.Msg{packet(4)}) {
+		t.Errorf("unrelayed() = %v, want only packet 4", msgs)
+	}
+
+	if err = progress.Reset(p); err != nil {
+		t.Fatal(err)
+	}
+	state, err = progress.Progress(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []PathProgress{{Path: p.String()}, {Path: p.Reverse().String()}}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("Progress() after Reset = %+v, want %+v", state, want)
+	}
+}
@@ -1,6 +1,7 @@
 package relayer
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"strings"
@@ -23,6 +24,7 @@ import (
 	commitment "github.com/cosmos/cosmos-sdk/x/ibc/23-commitment"
 	ibctypes "github.com/cosmos/cosmos-sdk/x/ibc/types"
 	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	tmtypes "github.com/tendermint/tendermint/types"
@@ -86,7 +88,8 @@ func (c *Chain) QueryClientConsensusState(height uint64) (clientTypes.ConsensusS
 	return clientTypes.NewConsensusStateResponse(c.PathEnd.ClientID, cs, res.Proof, res.Height), nil
 }
 
-// QueryClientState retrevies the latest consensus state for a client in state at a given height
+// QueryClientState retrevies the state of the client on the chain's path end, its
+// ClientState is nil if the client doesn't exist
 func (c *Chain) QueryClientState() (clientTypes.StateResponse, error) {
 	var conStateRes clientTypes.StateResponse
 
@@ -105,6 +108,11 @@ func (c *Chain) QueryClientState() (clientTypes.StateResponse, error) {
 		return conStateRes, err
 	}
 
+	// the client doesn't exist yet
+	if len(res.Value) == 0 {
+		return clientTypes.NewClientStateResponse(c.PathEnd.ClientID, nil, res.Proof, res.Height), nil
+	}
+
 	var cs exported.ClientState
 	if err := c.Cdc.UnmarshalBinaryLengthPrefixed(res.Value, &cs); err != nil {
 		return conStateRes, err
@@ -153,9 +161,12 @@ func (c *Chain) QueryConnectionsUsingClient(height int64) (clientConns connTypes
 		return clientConns, err
 	}
 
+	// a client without connections has no entry
 	var paths []string
-	if err := c.Cdc.UnmarshalBinaryLengthPrefixed(res.Value, &paths); err != nil {
-		return clientConns, err
+	if len(res.Value) != 0 {
+		if err := c.Cdc.UnmarshalBinaryLengthPrefixed(res.Value, &paths); err != nil {
+			return clientConns, err
+		}
 	}
 
 	return connTypes.NewClientConnectionsResponse(c.PathEnd.ClientID, paths, res.Proof, res.Height), nil
@@ -222,35 +233,192 @@ func (c *Chain) QueryChannel(height int64) (chanTypes.ChannelResponse, error) {
 	return chanTypes.NewChannelResponse(c.PathEnd.PortID, c.PathEnd.ChannelID, channel, res.Proof, res.Height), nil
 }
 
-// QueryTxs returns an array of transactions given a tag
-func (c *Chain) QueryTxs(height uint64, events []string) (*sdk.SearchTxsResult, error) {
+// CommitmentResponse is a packet commitment or acknowledgement hash along with
+// the proof of its inclusion in the chain's IBC store. Data is empty if the
+// store has no value at ProofPath.
+type CommitmentResponse struct {
+	Data        []byte           `json:"data" yaml:"data"`
+	Proof       commitment.Proof `json:"proof,omitempty" yaml:"proof,omitempty"`
+	ProofPath   commitment.Path  `json:"proof_path,omitempty" yaml:"proof_path,omitempty"`
+	ProofHeight uint64           `json:"proof_height,omitempty" yaml:"proof_height,omitempty"`
+}
+
+func newCommitmentResponse(data []byte, path string, proof *merkle.Proof, height int64) CommitmentResponse {
+	return CommitmentResponse{
+		Data:        data,
+		Proof:       commitment.Proof{Proof: proof},
+		ProofPath:   commitment.NewPath(strings.Split(path, "/")),
+		ProofHeight: uint64(height),
+	}
+}
+
+// QueryPacketCommitment returns the commitment of the packet with the given
+// sequence sent on the chain's path end
+func (c *Chain) QueryPacketCommitment(height int64, seq uint64) (CommitmentResponse, error) {
+	if !c.PathSet() {
+		return CommitmentResponse{}, ErrPathNotSet
+	}
+
+	req := abci.RequestQuery{
+		Path:   "store/ibc/key",
+		Data:   ibctypes.KeyPacketCommitment(c.PathEnd.PortID, c.PathEnd.ChannelID, seq),
+		Height: height,
+		Prove:  true,
+	}
+
+	res, err := c.QueryABCI(req)
+	if err != nil {
+		return CommitmentResponse{}, err
+	}
+
+	return newCommitmentResponse(res.Value, ibctypes.PacketCommitmentPath(c.PathEnd.PortID, c.PathEnd.ChannelID, seq), res.Proof, res.Height), nil
+}
+
+// QueryPacketAcknowledgement returns the acknowledgement written by the chain's
+// path end for the packet with the given sequence
+func (c *Chain) QueryPacketAcknowledgement(height int64, seq uint64) (CommitmentResponse, error) {
+	if !c.PathSet() {
+		return CommitmentResponse{}, ErrPathNotSet
+	}
+
+	req := abci.RequestQuery{
+		Path:   "store/ibc/key",
+		Data:   ibctypes.KeyPacketAcknowledgement(c.PathEnd.PortID, c.PathEnd.ChannelID, seq),
+		Height: height,
+		Prove:  true,
+	}
+
+	res, err := c.QueryABCI(req)
+	if err != nil {
+		return CommitmentResponse{}, err
+	}
+
+	return newCommitmentResponse(res.Value, ibctypes.PacketAcknowledgementPath(c.PathEnd.PortID, c.PathEnd.ChannelID, seq), res.Proof, res.Height), nil
+}
+
+// QueryNextSequenceRecv returns the sequence of the next packet the chain's path
+// end expects to receive on an ordered channel
+func (c *Chain) QueryNextSequenceRecv(height int64) (chanTypes.RecvResponse, error) {
+	if !c.PathSet() {
+		return chanTypes.RecvResponse{}, ErrPathNotSet
+	}
+
+	req := abci.RequestQuery{
+		Path:   "store/ibc/key",
+		Data:   ibctypes.KeyNextSequenceRecv(c.PathEnd.PortID, c.PathEnd.ChannelID),
+		Height: height,
+		Prove:  true,
+	}
+
+	res, err := c.QueryABCI(req)
+	if err != nil {
+		return chanTypes.RecvResponse{}, err
+	}
+
+	var seq uint64
+	if len(res.Value) == 8 {
+		seq = binary.BigEndian.Uint64(res.Value)
+	} else if len(res.Value) != 0 {
+		return chanTypes.RecvResponse{}, fmt.Errorf("malformed next sequence recv %X", res.Value)
+	}
+
+	return chanTypes.NewRecvResponse(c.PathEnd.PortID, c.PathEnd.ChannelID, seq, res.Proof, res.Height), nil
+}
+
+// defaultTxSearchPerPage is the page size used by QueryTxs, it is also the
+// largest page tendermint serves
+const defaultTxSearchPerPage = 100
+
+// TxSearchOptions bounds the results of QueryTxs
+type TxSearchOptions struct {
+	// MinHeight and MaxHeight bound the heights of the txs returned, inclusively.
+	// Zero leaves that end of the range open.
+	MinHeight int64
+	MaxHeight int64
+
+	// PerPage is the number of txs fetched per request, defaults to 100
+	PerPage int
+
+	// Limit stops the search once that many txs have been found, zero returns all
+	Limit int
+
+	// SkipBlocks skips fetching the block of each tx, the txs are returned
+	// without timestamps
+	SkipBlocks bool
+}
+
+func (o TxSearchOptions) perPage() int {
+	if o.PerPage <= 0 || o.PerPage > defaultTxSearchPerPage {
+		return defaultTxSearchPerPage
+	}
+	return o.PerPage
+}
+
+// query returns the tx search query for events bounded by the height range
+func (o TxSearchOptions) query(events []string) string {
+	events = append([]string{}, events...)
+	if o.MinHeight > 0 {
+		events = append(events, fmt.Sprintf("tx.height>=%d", o.MinHeight))
+	}
+	if o.MaxHeight > 0 {
+		events = append(events, fmt.Sprintf("tx.height<=%d", o.MaxHeight))
+	}
+	return strings.Join(events, " AND ")
+}
+
+// QueryTxs returns the transactions matching all of the given events in
+// ascending order, paging through the results as bounded by opts
+func (c *Chain) QueryTxs(events []string, opts TxSearchOptions) (*sdk.SearchTxsResult, error) {
 	if len(events) == 0 {
 		return nil, errors.New("must declare at least one event to search")
 	}
 
-	resTxs, err := c.Client.TxSearch(strings.Join(events, " AND "), true, 0, 10000, "asc")
-	if err != nil {
-		return nil, err
+	var (
+		query   = opts.query(events)
+		perPage = opts.perPage()
+		resTxs  []*ctypes.ResultTx
+		total   int
+		page    int
+	)
+	for page = 1; ; page++ {
+		res, err := c.Client.TxSearch(query, true, page, perPage, "asc")
+		if err != nil {
+			return nil, err
+		}
+
+		total = res.TotalCount
+		resTxs = append(resTxs, res.Txs...)
+
+		if len(res.Txs) < perPage || len(resTxs) >= total || (opts.Limit > 0 && len(resTxs) >= opts.Limit) {
+			break
+		}
+	}
+
+	if opts.Limit > 0 && len(resTxs) > opts.Limit {
+		resTxs = resTxs[:opts.Limit]
 	}
 
-	for _, tx := range resTxs.Txs {
+	for _, tx := range resTxs {
 		err := c.ValidateTxResult(tx)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	resBlocks, err := c.queryBlocksForTxResults(resTxs.Txs)
-	if err != nil {
-		return nil, err
+	resBlocks := make(map[int64]*ctypes.ResultBlock)
+	if !opts.SkipBlocks {
+		var err error
+		if resBlocks, err = c.queryBlocksForTxResults(resTxs); err != nil {
+			return nil, err
+		}
 	}
 
-	txs, err := c.formatTxResults(resTxs.Txs, resBlocks)
+	txs, err := c.formatTxResults(resTxs, resBlocks)
 	if err != nil {
 		return nil, err
 	}
 
-	result := sdk.NewSearchTxsResult(resTxs.TotalCount, len(txs), 0, 10000, txs)
+	result := sdk.NewSearchTxsResult(total, len(txs), page, perPage, txs)
 
 	return &result, nil
 }
@@ -308,7 +476,7 @@ func (c *Chain) QueryLatestHeight() (int64, error) {
 	}
 
 	if res.SyncInfo.CatchingUp {
-		return -1, fmt.Errorf("node at %s running chain %s not caught up", c.RPCAddr, c.ChainID)
+		return -1, fmt.Errorf("node at %s running chain %s not caught up", c.Client.ActiveAddr(), c.ChainID)
 	}
 
 	return res.SyncInfo.LatestBlockHeight, nil
@@ -446,14 +614,20 @@ func (c *Chain) formatTxResults(resTxs []*ctypes.ResultTx, resBlocks map[int64]*
 	return out, nil
 }
 
-// formatTxResult parses a tx into a TxResponse object
+// formatTxResult parses a tx into a TxResponse object, the timestamp is left
+// empty if resBlock is nil
 func (c *Chain) formatTxResult(resTx *ctypes.ResultTx, resBlock *ctypes.ResultBlock) (sdk.TxResponse, error) {
 	tx, err := parseTx(c.Cdc, resTx.Tx)
 	if err != nil {
 		return sdk.TxResponse{}, err
 	}
 
-	return sdk.NewResponseResultTx(resTx, tx, resBlock.Block.Time.Format(time.RFC3339)), nil
+	var timestamp string
+	if resBlock != nil {
+		timestamp = resBlock.Block.Time.Format(time.RFC3339)
+	}
+
+	return sdk.NewResponseResultTx(resTx, tx, timestamp), nil
 }
 
 // Takes some bytes and a codec and returns an sdk.Tx
@@ -0,0 +1,46 @@
+package relayer
+
+import "testing"
+
+func TestTxSearchOptionsQuery(t *testing.T) {
+	// spare capacity must not let the height bounds leak into the caller's slice
+	events := append(make([]string, 0, 3), "update_client.client_id='client'", "message.action='update_client'")
+
+	cases := []struct {
+		name string
+		opts TxSearchOptions
+		want string
+	}{
+		{"unbounded", TxSearchOptions{}, "update_client.client_id='client' AND message.action='update_client'"},
+		{"min height", TxSearchOptions{MinHeight: 10}, "update_client.client_id='client' AND message.action='update_client' AND tx.height>=10"},
+		{"max height", TxSearchOptions{MaxHeight: 20}, "update_client.client_id='client' AND message.action='update_client' AND tx.height<=20"},
+		{"min and max height", TxSearchOptions{MinHeight: 10, MaxHeight: 20}, "update_client.client_id='client' AND message.action='update_client' AND tx.height>=10 AND tx.height<=20"},
+	}
+
+	for _, tc := range cases {
+		if got := tc.opts.query(events); got != tc.want {
+			t.Errorf("%s: query() = %q, want %q", tc.name, got, tc.want)
+		}
+		if spare := events[:3][2]; spare != "" {
+			t.Errorf("%s: query() wrote %q into the events' backing array", tc.name, spare)
+		}
+	}
+}
+
+func TestTxSearchOptionsPerPage(t *testing.T) {
+	cases := []struct {
+		perPage int
+		want    int
+	}{
+		{0, defaultTxSearchPerPage},
+		{-1, defaultTxSearchPerPage},
+		{30, 30},
+		{defaultTxSearchPerPage + 1, defaultTxSearchPerPage},
+	}
+
+	for _, tc := range cases {
+		if got := (TxSearchOptions{PerPage: tc.perPage}).perPage(); got != tc.want {
+			t.Errorf("perPage() with PerPage %d = %d, want %d", tc.perPage, got, tc.want)
+		}
+	}
+}
@@ -1,9 +1,19 @@
 package relayer
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
 
 // Relay implements the algorithm described in ICS18 (https://github.com/cosmos/ics/tree/master/spec/ics-018-relayer-algorithms)
-func Relay(strategy string, c Chains, paths []Path) error {
+// The msgs of all paths are merged per destination chain before they are sent,
+// see sendRelayed. The progress made on each path is recorded in progress, which
+// may be nil.
+func Relay(strategy string, c Chains, paths []Path, progress *ProgressStore) error {
+	defer observeRelay(time.Now())
+	var relayed []relayedPath
 	for _, src := range c {
 		for _, path := range paths {
 			if path.Src.ChainID != src.ChainID {
@@ -12,6 +22,12 @@ func Relay(strategy string, c Chains, paths []Path) error {
 					return err
 				}
 
+				// Chains whose lite client has seen conflicting headers can't be trusted
+				if err = haltedErr(src, dst); err != nil {
+					src.logger.Error("not relaying", "path", path, "err", err)
+					continue
+				}
+
 				err = src.setPath(&path.Src)
 				if err != nil {
 					return err
@@ -28,26 +44,120 @@ func Relay(strategy string, c Chains, paths []Path) error {
 					return fmt.Errorf("Must pick a configurable relaying strategy")
 				}
 
-				msgs, err := Strategy(strategy)(src, dst)
+				msgs, err := Strategy(strategy)(src, dst, progress)
 				if err != nil {
 					return err
 				}
 
-				// Submit the transactions to src chain
-				srcRes, err := src.SendMsgs(msgs.Src)
-				if err != nil {
-					return err
+				if msgs.Ready() {
+					src.logger.Info("relaying", "path", path, "src-msgs", msgTypes(msgs.Src), "dst-msgs", msgTypes(msgs.Dst))
 				}
-				fmt.Println(srcRes)
+				relayed = append(relayed, relayedPath{src: src, dst: dst, srcEnd: *src.PathEnd, dstEnd: *dst.PathEnd, msgs: msgs})
+			}
+		}
+	}
 
-				// Submit the transactions to dst chain
-				dstRes, err := dst.SendMsgs(msgs.Dst)
-				if err != nil {
-					return err
-				}
-				fmt.Println(dstRes)
+	failed, err := sendRelayed(relayed)
+	if err != nil {
+		return err
+	}
+
+	for i := range relayed {
+		r := &relayed[i]
+		if !failed[r.src.ChainID] {
+			observeRelayed(&r.dstEnd, &r.srcEnd, r.msgs.Src)
+			r.src.PathEnd = &r.srcEnd
+			r.src.notifyTimeouts(r.msgs.Src)
+		}
+		if !failed[r.dst.ChainID] {
+			observeRelayed(&r.srcEnd, &r.dstEnd, r.msgs.Dst)
+			r.dst.PathEnd = &r.dstEnd
+			r.dst.notifyTimeouts(r.msgs.Dst)
+		}
+
+		// Failed txs are retried from the same heights next round
+		if !failed[r.src.ChainID] && !failed[r.dst.ChainID] {
+			if err = recordProgress(progress, Path{Src: r.srcEnd, Dst: r.dstEnd}, r.msgs); err != nil {
+				return err
 			}
 		}
 	}
 	return nil
 }
+
+// relayedPath is the result of a relay strategy on a path between the ends
+// srcEnd and dstEnd of the chains src and dst
+type relayedPath struct {
+	src, dst       *Chain
+	srcEnd, dstEnd PathEnd
+	msgs           *RelayMsgs
+}
+
+// sendRelayed sends the msgs of the relayed paths, those destined for the same
+// chain merged into the same txs so that each client is updated once, see
+// AssembleMsgs. It returns the chains on which a tx failed.
+func sendRelayed(relayed []relayedPath) (map[string]bool, error) {
+	type destination struct {
+		chain *Chain
+		ends  []PathEnd
+		msgs  []ClientMsgs
+	}
+
+	var order []string
+	dests := make(map[string]*destination)
+	add := func(c *Chain, end PathEnd, msgs []sdk.Msg) {
+		if len(msgs) == 0 {
+			return
+		}
+		d, ok := dests[c.ChainID]
+		if !ok {
+			d = &destination{chain: c}
+			dests[c.ChainID] = d
+			order = append(order, c.ChainID)
+		}
+		d.ends = append(d.ends, end)
+		d.msgs = append(d.msgs, ClientMsgs{ClientID: end.ClientID, Msgs: msgs})
+	}
+	for _, r := range relayed {
+		add(r.src, r.srcEnd, r.msgs.Src)
+		add(r.dst, r.dstEnd, r.msgs.Dst)
+	}
+
+	failed := make(map[string]bool)
+	for _, id := range order {
+		d := dests[id]
+
+		// txs carrying msgs of several paths don't belong to any one of them
+		d.chain.PathEnd = nil
+		if len(d.ends) == 1 {
+			d.chain.PathEnd = &d.ends[0]
+		}
+
+		res, err := d.chain.SendClientMsgs(d.msgs...)
+		if err != nil {
+			return nil, err
+		}
+		failed[id] = !txsSucceeded(res)
+	}
+	return failed, nil
+}
+
+// txsSucceeded returns true if none of the txs failed
+func txsSucceeded(res []sdk.TxResponse) bool {
+	for _, r := range res {
+		if r.Code != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// haltedErr returns an error for the first of the chains that has been halted
+func haltedErr(chains ...*Chain) error {
+	for _, c := range chains {
+		if err := c.Halted(); err != nil {
+			return fmt.Errorf("not relaying on halted chain %s: %w", c.ChainID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package relayer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/service"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// RPCClient is a tendermint RPC client for a chain served by an ordered list of
+// endpoints. Requests go to the active endpoint, if it can't be reached the client
+// fails over to the first healthy endpoint and retries the request once.
+// CheckHealth switches back to earlier endpoints in the list once they recover.
+type RPCClient struct {
+	chainID     string
+	timeout     time.Duration
+	maxBlockAge time.Duration
+	endpoints   []*rpcEndpoint
+	logger      log.Logger
+
+	mtx    sync.RWMutex
+	active int
+}
+
+// rpcEndpoint is a single RPC node serving the chain
+type rpcEndpoint struct {
+	addr   string
+	client *rpcclient.HTTP
+}
+
+// NewRPCClient returns an RPCClient for the given endpoints in order of preference.
+// Endpoints are unhealthy if their latest block is older than maxBlockAge, a
+// maxBlockAge of 0 disables that check.
+func NewRPCClient(chainID string, addrs []string, timeout, maxBlockAge time.Duration, logger log.Logger) (*RPCClient, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no RPC address configured for chain %s", chainID)
+	}
+
+	rc := &RPCClient{chainID: chainID, timeout: timeout, maxBlockAge: maxBlockAge, logger: logger}
+	for _, addr := range addrs {
+		client, err := newRPCClient(addr, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RPC address %s for chain %s: %w", addr, chainID, err)
+		}
+		rc.endpoints = append(rc.endpoints, &rpcEndpoint{addr: addr, client: client})
+	}
+
+	return rc, nil
+}
+
+// ActiveAddr returns the address of the endpoint requests are currently sent to
+func (rc *RPCClient) ActiveAddr() string {
+	return rc.activeEndpoint().addr
+}
+
+func (rc *RPCClient) activeEndpoint() *rpcEndpoint {
+	rc.mtx.RLock()
+	defer rc.mtx.RUnlock()
+	return rc.endpoints[rc.active]
+}
+
+// CheckHealth checks the endpoints in order and makes the first healthy one active.
+// An endpoint is healthy if it serves the right chain, is not catching up and its
+// latest block is recent.
+func (rc *RPCClient) CheckHealth() error {
+	var errs []string
+	for i, e := range rc.endpoints {
+		if err := rc.endpointHealthy(e); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		rc.mtx.Lock()
+		prev := rc.active
+		rc.active = i
+		rc.mtx.Unlock()
+
+		if prev != i {
+			rc.logger.Info("switched RPC endpoint", "from", rc.endpoints[prev].addr, "to", e.addr)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no healthy RPC endpoint for chain %s: %s", rc.chainID, strings.Join(errs, "; "))
+}
+
+func (rc *RPCClient) endpointHealthy(e *rpcEndpoint) error {
+	status, err := e.client.Status()
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s: %w", e.addr, err)
+	case status.NodeInfo.Network != rc.chainID:
+		return fmt.Errorf("%s: serves chain %s", e.addr, status.NodeInfo.Network)
+	case status.SyncInfo.CatchingUp:
+		return fmt.Errorf("%s: catching up", e.addr)
+	case rc.maxBlockAge > 0 && time.Since(status.SyncInfo.LatestBlockTime) > rc.maxBlockAge:
+		return fmt.Errorf("%s: latest block %d is older than %s", e.addr, status.SyncInfo.LatestBlockHeight, rc.maxBlockAge)
+	}
+	return nil
+}
+
+// StartHealthChecks runs CheckHealth every period, it returns immediately if there
+// is only one endpoint to choose from
+func (rc *RPCClient) StartHealthChecks(period time.Duration) {
+	if len(rc.endpoints) < 2 {
+		return
+	}
+
+	ticker := time.NewTicker(period)
+	for ; true; <-ticker.C {
+		if err := rc.CheckHealth(); err != nil {
+			rc.logger.Error("RPC health check failed", "err", err)
+		}
+	}
+}
+
+// do runs req against the active endpoint, failing over and retrying once if the
+// endpoint can't be reached
+func (rc *RPCClient) do(req func(*rpcclient.HTTP) error) error {
+	err := req(rc.activeEndpoint().client)
+	if err == nil || len(rc.endpoints) < 2 || !isConnectionError(err) {
+		return err
+	}
+
+	if herr := rc.CheckHealth(); herr != nil {
+		return fmt.Errorf("%w, failover failed: %s", err, herr)
+	}
+
+	return req(rc.activeEndpoint().client)
+}
+
+// isConnectionError returns true if err was caused by failing to reach the node
+// rather than by an error returned from it
+func isConnectionError(err error) bool {
+	for err != nil {
+		if _, ok := err.(net.Error); ok {
+			return true
+		}
+
+		switch e := err.(type) {
+		case interface{ Cause() error }:
+			if e.Cause() == err {
+				return false
+			}
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// Status calls /status on the active endpoint
+func (rc *RPCClient) Status() (res *ctypes.ResultStatus, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.Status()
+		return
+	})
+	return
+}
+
+// ABCIQueryWithOptions calls /abci_query on the active endpoint
+func (rc *RPCClient) ABCIQueryWithOptions(path string, data []byte, opts rpcclient.ABCIQueryOptions) (res *ctypes.ResultABCIQuery, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.ABCIQueryWithOptions(path, data, opts)
+		return
+	})
+	return
+}
+
+// Block calls /block on the active endpoint
+func (rc *RPCClient) Block(height *int64) (res *ctypes.ResultBlock, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.Block(height)
+		return
+	})
+	return
+}
+
+// BlockResults calls /block_results on the active endpoint
+func (rc *RPCClient) BlockResults(height *int64) (res *ctypes.ResultBlockResults, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.BlockResults(height)
+		return
+	})
+	return
+}
+
+// Commit calls /commit on the active endpoint
+func (rc *RPCClient) Commit(height *int64) (res *ctypes.ResultCommit, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.Commit(height)
+		return
+	})
+	return
+}
+
+// Validators calls /validators on the active endpoint
+func (rc *RPCClient) Validators(height *int64, page, perPage int) (res *ctypes.ResultValidators, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.Validators(height, page, perPage)
+		return
+	})
+	return
+}
+
+// Tx calls /tx on the active endpoint
+func (rc *RPCClient) Tx(hash []byte, prove bool) (res *ctypes.ResultTx, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.Tx(hash, prove)
+		return
+	})
+	return
+}
+
+// TxSearch calls /tx_search on the active endpoint
+func (rc *RPCClient) TxSearch(query string, prove bool, page, perPage int, orderBy string) (res *ctypes.ResultTxSearch, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.TxSearch(query, prove, page, perPage, orderBy)
+		return
+	})
+	return
+}
+
+// BroadcastTxSync calls /broadcast_tx_sync on the active endpoint. Resubmitting
+// a tx after failing over is safe, the mempool rejects duplicates.
+func (rc *RPCClient) BroadcastTxSync(tx tmtypes.Tx) (res *ctypes.ResultBroadcastTx, err error) {
+	err = rc.do(func(c *rpcclient.HTTP) (err error) {
+		res, err = c.BroadcastTxSync(tx)
+		return
+	})
+	return
+}
+
+// eventBufferSize is the number of events buffered per subscription, events are
+// dropped by the websocket client when the buffer is full
+const eventBufferSize = 100
+
+// Subscribe subscribes to query on the websocket of the endpoint at addr, connecting
+// to it first if needed. Subscriptions stay on that endpoint when the client fails
+// over, callers should resubscribe when ActiveAddr changes.
+func (rc *RPCClient) Subscribe(ctx context.Context, addr, subscriber, query string) (<-chan ctypes.ResultEvent, error) {
+	e, err := rc.endpoint(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = e.client.Start(); err != nil && err != service.ErrAlreadyStarted {
+		return nil, fmt.Errorf("failed to connect to websocket of %s: %w", addr, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rc.timeout)
+	defer cancel()
+	return e.client.Subscribe(ctx, subscriber, query, eventBufferSize)
+}
+
+// UnsubscribeAll removes all subscriptions of subscriber on the endpoint at addr
+func (rc *RPCClient) UnsubscribeAll(addr, subscriber string) error {
+	e, err := rc.endpoint(addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rc.timeout)
+	defer cancel()
+	return e.client.UnsubscribeAll(ctx, subscriber)
+}
+
+// Stop closes the websocket connections of all endpoints
+func (rc *RPCClient) Stop() error {
+	var out error
+	for _, e := range rc.endpoints {
+		if !e.client.IsRunning() {
+			continue
+		}
+		if err := e.client.Stop(); err != nil {
+			out = fmt.Errorf("%s: %w", e.addr, err)
+		}
+	}
+	return out
+}
+
+func (rc *RPCClient) endpoint(addr string) (*rpcEndpoint, error) {
+	for _, e := range rc.endpoints {
+		if e.addr == addr {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not an RPC endpoint of chain %s", addr, rc.chainID)
+}
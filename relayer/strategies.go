@@ -1,7 +1,10 @@
 package relayer
 
 import (
+	"fmt"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	clientExported "github.com/cosmos/cosmos-sdk/x/ibc/02-client/exported"
 	chanState "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/exported"
 	tmclient "github.com/cosmos/cosmos-sdk/x/ibc/07-tendermint"
 	xferTypes "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/types"
@@ -25,28 +28,31 @@ func Strategy(name string) RelayStrategy {
 	}
 }
 
-// RelayStrategy describes the function signature for a relay strategy
-type RelayStrategy func(src, dst *Chain) (*RelayMsgs, error)
+// RelayStrategy describes the function signature for a relay strategy. Strategies
+// resume scanning the chains from the progress recorded for the path.
+type RelayStrategy func(src, dst *Chain, progress *ProgressStore) (*RelayMsgs, error)
 
 // RelayMsgs contains the msgs that need to be sent to both a src and dst chain
 // after a given relay round
 type RelayMsgs struct {
 	Src []sdk.Msg
 	Dst []sdk.Msg
+
+	// SrcScanned and DstScanned are the heights up to which the strategy scanned
+	// src and dst, they are recorded as progress once the msgs are committed
+	SrcScanned int64
+	DstScanned int64
 }
 
 // Ready returns true if there are messages to relay
 func (r *RelayMsgs) Ready() bool {
-	if len(r.Src) == 0 && len(r.Dst) == 0 {
-		return true
-	}
-	return false
+	return len(r.Src) != 0 || len(r.Dst) != 0
 }
 
 // NaiveRelayStrategy returns the RelayMsgs that need to be run to relay between
 // src and dst chains for all pending messages. Will also create or repair
 // connections and channels
-func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
+func NaiveRelayStrategy(src, dst *Chain, progress *ProgressStore) (*RelayMsgs, error) {
 	out := &RelayMsgs{Src: []sdk.Msg{}, Dst: []sdk.Msg{}}
 
 	hs, err := UpdatesWithHeaders(src, dst)
@@ -54,39 +60,20 @@ func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
 		return nil, err
 	}
 
-	// ICS2 : Clients - DstClient
-	// Fetch current client state
-	srcClientState, err := src.QueryClientConsensusState(uint64(hs[src.ChainID].Height))
+	// ICS2 : Clients
+	// Create the client on each end if it doesn't exist, or update it to the
+	// latest trusted header of the chain it tracks
+	srcClientMsgs, err := clientMsgs(src, hs[dst.ChainID])
 	if err != nil {
 		return nil, err
 	}
+	out.Src = append(out.Src, srcClientMsgs...)
 
-	switch {
-	// If there is no matching client found, create it
-	// TODO: ensure that this is the right condition
-	case srcClientState.ConsensusState.GetRoot().GetHash() == nil:
-		out.Src = append(out.Src, src.CreateClient(hs[dst.ChainID]))
-
-	// If there client is found update it with latest header
-	case srcClientState.ProofHeight < uint64(hs[dst.ChainID].Height):
-		out.Src = append(out.Src, src.UpdateClient(hs[dst.ChainID]))
-	}
-
-	dstClientState, err := dst.QueryClientConsensusState(uint64(hs[dst.ChainID].Height))
+	dstClientMsgs, err := clientMsgs(dst, hs[src.ChainID])
 	if err != nil {
 		return nil, err
 	}
-
-	switch {
-	// If there is no client found matching, create the client
-	// TODO: ensure that this is the right condition
-	case dstClientState.ConsensusState.GetRoot().GetHash() == nil:
-		out.Dst = append(out.Dst, dst.CreateClient(hs[src.ChainID]))
-
-	// If there client is found update it with latest header
-	case dstClientState.ProofHeight < uint64(hs[src.ChainID].Height):
-		out.Dst = append(out.Dst, dst.UpdateClient(hs[src.ChainID]))
-	}
+	out.Dst = append(out.Dst, dstClientMsgs...)
 
 	// Return here and move on to the next iteration
 	if out.Ready() {
@@ -134,6 +121,18 @@ func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
 
 	// ICS?: Packet Messages
 	// - Determine if any packets, acknowledgements, or timeouts need to be relayed
+	// - Only scan the blocks that haven't been scanned on a previous run
+	path := Path{Src: *src.PathEnd, Dst: *dst.PathEnd}
+	srcScanned, err := progress.LastHeight(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dstScanned, err := progress.LastHeight(path.Reverse())
+	if err != nil {
+		return nil, err
+	}
+
 	for _, srcChan := range channels {
 		if srcChan.Channel.GetCounterparty().GetChannelID() == dst.PathEnd.ChannelID {
 			// Deal with packets
@@ -141,7 +140,11 @@ func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
 
 			// First, scan logs for sent packets and relay all of them
 			// TODO: This is currently incorrect and will change
-			srcRes, err := src.QueryTxs(uint64(hs[src.ChainID].Height), []string{"type:transfer"})
+			srcRes, err := src.QueryTxs([]string{"type:transfer"}, TxSearchOptions{
+				MinHeight:  srcScanned + 1,
+				MaxHeight:  hs[src.ChainID].Height,
+				SkipBlocks: true,
+			})
 			if err != nil {
 				return nil, err
 			}
@@ -156,7 +159,11 @@ func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
 
 			// Then, scan logs for received packets and relay acknowledgements
 			// TODO: This is currently incorrect and will change
-			dstRes, err := dst.QueryTxs(uint64(hs[dst.ChainID].Height), []string{"type:recv_packet"})
+			dstRes, err := dst.QueryTxs([]string{"type:recv_packet"}, TxSearchOptions{
+				MinHeight:  dstScanned + 1,
+				MaxHeight:  hs[dst.ChainID].Height,
+				SkipBlocks: true,
+			})
 			if err != nil {
 				return nil, err
 			}
@@ -168,6 +175,14 @@ func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
 					}
 				}
 			}
+
+			// Packets delivered by an earlier round are in txs scanned again
+			// after a failed round, don't deliver them twice
+			if out.Dst, err = unrelayed(progress, path, out.Dst); err != nil {
+				return nil, err
+			}
+
+			out.SrcScanned, out.DstScanned = hs[src.ChainID].Height, hs[dst.ChainID].Height
 		}
 	}
 
@@ -175,7 +190,51 @@ func NaiveRelayStrategy(src, dst *Chain) (*RelayMsgs, error) {
 	return out, nil
 }
 
-// Group the keybase and height queries here
+// clientAction is what the client stage of a strategy has to do with a client
+type clientAction int
+
+const (
+	clientUpToDate clientAction = iota
+	clientCreate
+	clientUpdate
+	clientFrozen
+)
+
+// clientStage decides what to do with a client given its state, nil if it doesn't
+// exist, and the latest height of the tracked chain trusted by the lite client
+func clientStage(cs clientExported.ClientState, trustedHeight int64) clientAction {
+	switch {
+	case cs == nil:
+		return clientCreate
+	case cs.IsFrozen():
+		return clientFrozen
+	case cs.GetLatestHeight() < uint64(trustedHeight):
+		return clientUpdate
+	default:
+		return clientUpToDate
+	}
+}
+
+// clientMsgs returns the msgs that create the client on c's path end, or update
+// it to dstHeader, the latest trusted header of the chain it tracks
+func clientMsgs(c *Chain, dstHeader *tmclient.Header) ([]sdk.Msg, error) {
+	cs, err := c.QueryClientState()
+	if err != nil {
+		return nil, err
+	}
+
+	switch clientStage(cs.ClientState, dstHeader.Height) {
+	case clientCreate:
+		return []sdk.Msg{c.CreateClient(dstHeader)}, nil
+	case clientUpdate:
+		return []sdk.Msg{c.UpdateClient(dstHeader)}, nil
+	case clientFrozen:
+		return nil, fmt.Errorf("client %s on %s is frozen after misbehaviour", c.PathEnd.ClientID, c.ChainID)
+	default:
+		return nil, nil
+	}
+}
+
 func addrsHeaders(src, dst *Chain) (srcAddr, dstAddr sdk.AccAddress, srcHeader, dstHeader *tmclient.Header, err error) {
 	// Signing key for src chain
 	srcAddr, err = src.GetAddress()
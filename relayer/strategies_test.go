@@ -0,0 +1,50 @@
+package relayer
+
+import (
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	clientExported "github.com/cosmos/cosmos-sdk/x/ibc/02-client/exported"
+	clientTypes "github.com/cosmos/cosmos-sdk/x/ibc/02-client/types"
+	tmclient "github.com/cosmos/cosmos-sdk/x/ibc/07-tendermint"
+)
+
+func TestClientStage(t *testing.T) {
+	cases := []struct {
+		name          string
+		cs            clientExported.ClientState
+		trustedHeight int64
+		want          clientAction
+	}{
+		{"missing", nil, 10, clientCreate},
+		{"frozen", tmclient.ClientState{ID: "client", LatestHeight: 5, FrozenHeight: 3}, 10, clientFrozen},
+		{"stale", tmclient.ClientState{ID: "client", LatestHeight: 5}, 10, clientUpdate},
+		{"up to date", tmclient.ClientState{ID: "client", LatestHeight: 10}, 10, clientUpToDate},
+		{"ahead of lite client", tmclient.ClientState{ID: "client", LatestHeight: 15}, 10, clientUpToDate},
+	}
+
+	for _, tc := range cases {
+		if got := clientStage(tc.cs, tc.trustedHeight); got != tc.want {
+			t.Errorf("%s: clientStage() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRelayMsgsReady(t *testing.T) {
+	msg := clientTypes.MsgUpdateClient{ClientID: "client"}
+	cases := []struct {
+		name string
+		msgs RelayMsgs
+		want bool
+	}{
+		{"empty", RelayMsgs{}, false},
+		{"src msgs", RelayMsgs{Src: []sdk.Msg{msg}}, true},
+		{"dst msgs", RelayMsgs{Dst: []sdk.Msg{msg}}, true},
+	}
+
+	for _, tc := range cases {
+		if got := tc.msgs.Ready(); got != tc.want {
+			t.Errorf("%s: Ready() = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
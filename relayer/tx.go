@@ -2,9 +2,12 @@ package relayer
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/evidence"
 	clientExported "github.com/cosmos/cosmos-sdk/x/ibc/02-client/exported"
 	clientTypes "github.com/cosmos/cosmos-sdk/x/ibc/02-client/types"
 	connState "github.com/cosmos/cosmos-sdk/x/ibc/03-connection/exported"
@@ -12,6 +15,7 @@ import (
 	chanState "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/exported"
 	chanTypes "github.com/cosmos/cosmos-sdk/x/ibc/04-channel/types"
 	tmclient "github.com/cosmos/cosmos-sdk/x/ibc/07-tendermint"
+	xferTypes "github.com/cosmos/cosmos-sdk/x/ibc/20-transfer/types"
 	commitment "github.com/cosmos/cosmos-sdk/x/ibc/23-commitment"
 )
 
@@ -21,9 +25,10 @@ var (
 	defaultIBCVersions = []string{defaultIBCVersion}
 )
 
-// CreateConnection creates a connection between two chains given src and dst client IDs
-func (src *Chain) CreateConnection(dst *Chain, srcClientID, dstClientID, srcConnectionID, dstConnectionID string, timeout time.Duration) error {
-	ticker := time.NewTicker(timeout)
+// CreateConnection creates a connection between two chains given src and dst client IDs,
+// running a handshake step every interval until the connection is open on both ends
+func (src *Chain) CreateConnection(dst *Chain, srcClientID, dstClientID, srcConnectionID, dstConnectionID string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
 	for ; true; <-ticker.C {
 
 		if err := src.SetNewPathConnection(srcClientID, srcConnectionID); err != nil {
@@ -44,20 +49,20 @@ func (src *Chain) CreateConnection(dst *Chain, srcClientID, dstClientID, srcConn
 		}
 
 		// Submit the transactions to src chain
-		srcRes, err := src.SendMsgs(msgs.Src)
-		if err != nil {
+		if _, err = src.SendMsgs(msgs.Src); err != nil {
 			return err
 		}
-		src.logger.Info(srcRes.String())
 
 		// Submit the transactions to dst chain
-		dstRes, err := dst.SendMsgs(msgs.Dst)
-		if err != nil {
+		if _, err = dst.SendMsgs(msgs.Dst); err != nil {
 			return err
 		}
-		src.logger.Info(dstRes.String())
 	}
 
+	src.notify(NotifyConnectionOpen, fmt.Sprintf("connection %s on %s and %s on %s is open",
+		src.PathEnd.ConnectionID, src.ChainID, dst.PathEnd.ConnectionID, dst.ChainID),
+		"connection-id", src.PathEnd.ConnectionID, "counterparty-chain-id", dst.ChainID,
+		"counterparty-connection-id", dst.PathEnd.ConnectionID)
 	return nil
 }
 
@@ -124,10 +129,11 @@ func (src *Chain) CreateConnectionStep(dst *Chain) (*RelayMsgs, error) {
 	return out, nil
 }
 
-// CreateChannel creates a connection between two chains given src and dst client IDs
+// CreateChannel creates a channel between two chains given src and dst client IDs,
+// running a handshake step every interval until the channel is open on both ends
 func (src *Chain) CreateChannel(dst *Chain, srcClientID, dstClientID, srcConnectionID, dstConnectionID,
-	srcChannelID, dstChannelID, srcPortID, dstPortID string, timeout time.Duration, ordering chanState.Order) error {
-	ticker := time.NewTicker(timeout)
+	srcChannelID, dstChannelID, srcPortID, dstPortID string, interval time.Duration, ordering chanState.Order) error {
+	ticker := time.NewTicker(interval)
 	for ; true; <-ticker.C {
 		if err := src.SetNewFullPath(srcClientID, srcConnectionID, srcChannelID, srcPortID); err != nil {
 			return err
@@ -147,20 +153,20 @@ func (src *Chain) CreateChannel(dst *Chain, srcClientID, dstClientID, srcConnect
 		}
 
 		// Submit the transactions to src chain
-		srcRes, err := src.SendMsgs(msgs.Src)
-		if err != nil {
+		if _, err = src.SendMsgs(msgs.Src); err != nil {
 			return err
 		}
-		src.logger.Info(srcRes.String())
 
 		// Submit the transactions to dst chain
-		dstRes, err := dst.SendMsgs(msgs.Dst)
-		if err != nil {
+		if _, err = dst.SendMsgs(msgs.Dst); err != nil {
 			return err
 		}
-		src.logger.Info(dstRes.String())
 	}
 
+	src.notify(NotifyChannelOpen, fmt.Sprintf("channel %s on %s and %s on %s is open",
+		src.PathEnd.ChannelID, src.ChainID, dst.PathEnd.ChannelID, dst.ChainID),
+		"channel-id", src.PathEnd.ChannelID, "port-id", src.PathEnd.PortID, "counterparty-chain-id", dst.ChainID,
+		"counterparty-channel-id", dst.PathEnd.ChannelID, "counterparty-port-id", dst.PathEnd.PortID)
 	return nil
 }
 
@@ -290,18 +296,79 @@ func (c *Chain) ChanCloseConfirm(dstChanState chanTypes.ChannelResponse) sdk.Msg
 	return chanTypes.NewMsgChannelCloseConfirm(c.PathEnd.PortID, c.PathEnd.ChannelID, dstChanState.Proof, dstChanState.ProofHeight, c.MustGetAddress())
 }
 
-// SendMsg wraps the msg in a stdtx, signs and sends it
-func (c *Chain) SendMsg(datagram sdk.Msg) (sdk.TxResponse, error) {
-	return c.SendMsgs([]sdk.Msg{datagram})
+// RecvPacket creates a MsgPacket delivering a packet sent by the counterparty,
+// proven by the counterparty's commitment to it
+func (c *Chain) RecvPacket(packet chanTypes.Packet, dstCommitment CommitmentResponse) sdk.Msg {
+	return chanTypes.NewMsgPacket(packet, dstCommitment.Proof, dstCommitment.ProofHeight, c.MustGetAddress())
 }
 
-// SendMsgs wraps the msgs in a stdtx, signs and sends it
-func (c *Chain) SendMsgs(datagrams []sdk.Msg) (sdk.TxResponse, error) {
+// AckPacket creates a MsgAcknowledgement for a packet sent by c, proven by the
+// acknowledgement the counterparty wrote when receiving it
+func (c *Chain) AckPacket(packet chanTypes.Packet, ack chanState.PacketDataI, dstAck CommitmentResponse) sdk.Msg {
+	return chanTypes.NewMsgAcknowledgement(packet, ack, dstAck.Proof, dstAck.ProofHeight, c.MustGetAddress())
+}
 
-	txBytes, err := c.BuildAndSignTx(datagrams)
-	if err != nil {
-		return sdk.TxResponse{}, err
+// TimeoutPacket creates a MsgTimeout for a packet sent by c. The proof is of the
+// counterparty's next sequence recv on ordered channels and of the absence of an
+// acknowledgement on unordered ones.
+func (c *Chain) TimeoutPacket(packet chanTypes.Packet, nextSequenceRecv uint64, proof commitment.Proof, proofHeight uint64) sdk.Msg {
+	return chanTypes.NewMsgTimeout(packet, nextSequenceRecv, proof, proofHeight, c.MustGetAddress())
+}
+
+// Transfer creates a MsgTransfer sending amount from c's key over its path end to
+// receiver on the counterparty. c is the source of the tokens unless every coin
+// is a voucher received over the path end, i.e. its denom is prefixed with the
+// path end's port and channel, in which case the vouchers are sent back.
+func (c *Chain) Transfer(amount sdk.Coins, receiver sdk.AccAddress) xferTypes.MsgTransfer {
+	prefix := xferTypes.GetDenomPrefix(c.PathEnd.PortID, c.PathEnd.ChannelID)
+	source := false
+	for _, coin := range amount {
+		if !strings.HasPrefix(coin.Denom, prefix) {
+			source = true
+		}
 	}
+	return xferTypes.NewMsgTransfer(c.PathEnd.PortID, c.PathEnd.ChannelID, amount, c.MustGetAddress(), receiver, source)
+}
+
+// SubmitMisbehaviour creates a MsgSubmitEvidence freezing the client on c's path
+// end with evidence of the tracked chain's misbehaviour
+func (c *Chain) SubmitMisbehaviour(ev tmclient.Evidence) evidence.MsgSubmitEvidence {
+	return evidence.NewMsgSubmitEvidence(ev, c.MustGetAddress())
+}
 
-	return c.BroadcastTxCommit(txBytes)
+// SendMsg wraps the msg in a stdtx, signs and sends it, see sendTx
+func (c *Chain) SendMsg(datagram sdk.Msg) (sdk.TxResponse, error) {
+	return c.sendTx([]sdk.Msg{datagram})
+}
+
+// SendMsgs wraps the msgs in stdtxs, signs and sends them. Client updates are
+// deduplicated and sent first, see AssembleMsgs, the proofs of the msgs being
+// verified by the client of the chain's path end. The msgs are split into as
+// many txs as the chain's MaxMsgsPerTx and MaxTxBytes require, see BatchMsgs. The
+// txs are sent one after the other until one fails, the responses of those sent
+// are returned.
+func (c *Chain) SendMsgs(datagrams []sdk.Msg) ([]sdk.TxResponse, error) {
+	var clientID string
+	if c.PathEnd != nil {
+		clientID = c.PathEnd.ClientID
+	}
+	return c.SendClientMsgs(ClientMsgs{ClientID: clientID, Msgs: datagrams})
+}
+
+// SendClientMsgs is SendMsgs for msgs of several paths to the chain, merged into
+// the same txs, the proofs of each group's msgs being verified by its client
+func (c *Chain) SendClientMsgs(msgs ...ClientMsgs) ([]sdk.TxResponse, error) {
+	var out []sdk.TxResponse
+	for _, batch := range c.BatchMsgs(AssembleMsgs(msgs...)) {
+		res, err := c.sendTx(batch)
+		if err != nil {
+			return out, err
+		}
+
+		out = append(out, res)
+		if res.Code != 0 {
+			return out, nil
+		}
+	}
+	return out, nil
 }
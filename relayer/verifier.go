@@ -1,11 +1,15 @@
 package relayer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,8 +19,10 @@ import (
 	lite "github.com/tendermint/tendermint/lite2"
 	litep "github.com/tendermint/tendermint/lite2/provider"
 	litehttp "github.com/tendermint/tendermint/lite2/provider/http"
+	"github.com/tendermint/tendermint/lite2/store"
 	dbs "github.com/tendermint/tendermint/lite2/store/db"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
 	dbm "github.com/tendermint/tm-db"
 )
 
@@ -26,8 +32,9 @@ func (c *Chain) StartUpdatingLiteClient(period time.Duration) {
 	for ; true; <-ticker.C {
 		err := c.UpdateLiteDBToLatestHeader()
 		if err != nil {
-			fmt.Println(err.Error())
+			c.logger.Error("failed to update lite client", "err", err)
 		}
+		c.observeHeights()
 	}
 }
 
@@ -49,29 +56,58 @@ func UpdatesWithHeaders(chains ...*Chain) (map[string]*tmclient.Header, error) {
 	return GetLatestHeaders(chains...)
 }
 
-// UpdateLiteDBToLatestHeader spins up an instance of the lite client as part of the chain.
+// UpdateLiteDBToLatestHeader updates the chain's lite client to the latest header
+// of the primary provider and prunes the lite database
 func (c *Chain) UpdateLiteDBToLatestHeader() error {
-	// create database connection
-	db, df, err := c.NewLiteDB()
+	if err := c.Halted(); err != nil {
+		return err
+	}
+
+	c.liteMtx.Lock()
+	defer c.liteMtx.Unlock()
+
+	lc, err := c.liteClient()
 	if err != nil {
 		return err
 	}
-	defer df()
 
-	// initialise lite client
-	lc, err := c.InitLiteClientWithoutTrust(db)
+	// check the witnesses agree with the primary before trusting a new header
+	sh, err := lc.Primary().SignedHeader(0)
 	if err != nil {
 		return err
 	}
+	if err = c.haltOnConflict(c.checkWitnesses(sh)); err != nil {
+		return err
+	}
 
 	now := time.Now()
 
 	// remove expired headers
 	lc.RemoveNoLongerTrustedHeaders(now)
 
+	last, err := lc.LastTrustedHeight()
+	if err != nil {
+		return err
+	}
+
 	// sync lite client to the most recent header of the primary provider
-	return lc.Update(now)
+	if err = lc.Update(now); err != nil {
+		return err
+	}
 
+	// the headers trusted on the way to the latest one are checked as well
+	if err = c.haltOnConflict(c.checkTrustedSince(lc, last, now)); err != nil {
+		return err
+	}
+
+	// drop the headers beyond the configured cache size
+	db, err := c.openLiteDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = pruneLiteStore(db, c.LiteCacheSize)
+	return err
 }
 
 type safeChainErrors struct {
@@ -87,13 +123,8 @@ func UpdateLiteDBsToLatestHeaders(chains ...*Chain) error {
 		go func(errs *safeChainErrors, wg *sync.WaitGroup, chain *Chain) {
 			defer wg.Done()
 			err := chain.UpdateLiteDBToLatestHeader()
-			if err != nil {
-				errs.Lock()
-				errs.Map[chain] = err
-				errs.Unlock()
-			}
 			errs.Lock()
-			errs.Map[chain] = nil
+			errs.Map[chain] = err
 			errs.Unlock()
 		}(&errs, &wg, chain)
 	}
@@ -107,39 +138,223 @@ func UpdateLiteDBsToLatestHeaders(chains ...*Chain) error {
 	return out
 }
 
-// InitLiteClientWithoutTrust reads the trusted period off of the chain
-func (c *Chain) InitLiteClientWithoutTrust(db *dbm.GoLevelDB) (*lite.Client, error) {
-	httpProvider, err := litehttp.New(c.ChainID, c.RPCAddr)
+// liteClient returns the chain's lite client, creating it from the trusted store
+// on first use
+// CONTRACT: c.liteMtx must be held
+func (c *Chain) liteClient() (*lite.Client, error) {
+	if c.lc != nil {
+		return c.lc, nil
+	}
+
+	db, err := c.openLiteDB()
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: provide actual witnesses!
-	lc, err := lite.NewClientFromTrustedStore(c.ChainID, c.TrustingPeriod, httpProvider,
-		[]litep.Provider{httpProvider}, dbs.New(db, ""),
+	primary, witnesses := c.liteProviders()
+	lc, err := lite.NewClientFromTrustedStore(c.ChainID, c.TrustingPeriod, primary,
+		witnesses, dbs.New(db, ""),
 		lite.Logger(log.NewTMLogger(log.NewSyncWriter(ioutil.Discard))))
 	if err != nil {
 		return nil, err
 	}
 
-	err = lc.Update(time.Now())
+	c.lc = lc
+	return lc, nil
+}
+
+// liteProviders returns the primary provider for the lite client, backed by the
+// chain's RPC client, and the witnesses it is checked against. Without configured
+// witnesses the primary is its own witness, which means forks are not detected.
+func (c *Chain) liteProviders() (litep.Provider, []litep.Provider) {
+	primary := litehttp.NewWithClient(c.ChainID, c.Client)
+	if len(c.witnesses) == 0 {
+		return primary, []litep.Provider{primary}
+	}
+	return primary, c.witnesses
+}
+
+// checkWitnesses compares sh, a header from the primary, to the header each
+// witness has at the same height, returning ErrConflictingHeaders if any of them
+// differ
+func (c *Chain) checkWitnesses(sh *tmtypes.SignedHeader) error {
+	if len(c.witnesses) == 0 {
+		return nil
+	}
+
+	for i, w := range c.witnesses {
+		alt, err := w.SignedHeader(sh.Height)
+		if err != nil {
+			return fmt.Errorf("failed to fetch header %d from witness %s: %w", sh.Height, c.Witnesses[i], err)
+		}
+
+		if !bytes.Equal(sh.Hash(), alt.Hash()) {
+			return &ErrConflictingHeaders{
+				ChainID: c.ChainID, Height: sh.Height, Primary: c.Client.ActiveAddr(), Witness: c.Witnesses[i],
+				PrimaryHeader: sh, WitnessHeader: alt,
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTrustedSince compares the headers the lite client trusts above height
+// after, such as those verified while bisecting to the latest header, to the
+// witnesses' headers
+// CONTRACT: c.liteMtx must be held
+func (c *Chain) checkTrustedSince(lc *lite.Client, after int64, now time.Time) error {
+	if len(c.witnesses) == 0 {
+		return nil
+	}
+
+	db, err := c.openLiteDB()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return lc, nil
+	heights, err := liteStoreHeights(db)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range heights {
+		if h <= after {
+			continue
+		}
+
+		sh, err := lc.TrustedHeader(h, now)
+		if err != nil {
+			return err
+		}
+		if err = c.checkWitnesses(sh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// haltOnConflict halts the chain if err is ErrConflictingHeaders, err is returned
+func (c *Chain) haltOnConflict(err error) error {
+	var conflict *ErrConflictingHeaders
+	if errors.As(err, &conflict) {
+		c.halt(conflict)
+	}
+	return err
+}
+
+// Halted returns the reason relaying on the chain has been halted or nil if it has not
+func (c *Chain) Halted() error {
+	c.haltMtx.Lock()
+	defer c.haltMtx.Unlock()
+
+	if !c.haltLoaded {
+		c.haltErr = c.loadHalt()
+		c.haltLoaded = true
+	}
+	return c.haltErr
+}
+
+// ClearHalt resumes lite client updates and relaying on a halted chain, it is
+// up to the operator to resolve the conflict first
+func (c *Chain) ClearHalt() error {
+	c.haltMtx.Lock()
+	defer c.haltMtx.Unlock()
+
+	if err := os.Remove(c.haltFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	c.haltErr, c.haltLoaded = nil, true
+	return nil
+}
+
+// halt stops all further lite client updates and relaying on the chain, also
+// after a restart until ClearHalt is called, and reports the conflicting headers
+func (c *Chain) halt(conflict *ErrConflictingHeaders) {
+	c.haltMtx.Lock()
+	c.haltErr, c.haltLoaded = conflict, true
+	c.haltMtx.Unlock()
+
+	if err := c.saveHalt(conflict); err != nil {
+		c.logger.Error("failed to persist halt", "err", err)
+	}
+
+	c.logger.Error("HALTING", "err", conflict)
+	c.notify(NotifyChainHalted, conflict.Error())
+	file, err := c.writeEvidence(conflict)
+	if err != nil {
+		c.logger.Error("failed to write evidence", "err", err)
+		return
+	}
+	c.logger.Info("evidence of conflicting headers written", "file", file)
+}
+
+// writeEvidence saves both conflicting headers to the lite directory so they can
+// be submitted to the chain or inspected by an operator
+func (c *Chain) writeEvidence(conflict *ErrConflictingHeaders) (string, error) {
+	bz, err := c.Cdc.MarshalJSONIndent(conflict, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(liteDir(c.HomePath), "evidence")
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, fmt.Sprintf("%s-%d.json", c.ChainID, conflict.Height))
+	return file, ioutil.WriteFile(file, bz, 0600)
+}
+
+// haltFile is where the conflict that halted the chain is kept
+func (c *Chain) haltFile() string {
+	return filepath.Join(liteDir(c.HomePath), fmt.Sprintf("%s.halted", c.ChainID))
+}
+
+func (c *Chain) saveHalt(conflict *ErrConflictingHeaders) error {
+	bz, err := c.Cdc.MarshalJSON(conflict)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(liteDir(c.HomePath), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.haltFile(), bz, 0600)
+}
+
+// loadHalt returns the persisted conflict that halted the chain, nil if it hasn't
+// been halted. A halt that can't be read still halts the chain.
+func (c *Chain) loadHalt() error {
+	bz, err := ioutil.ReadFile(c.haltFile())
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("chain %s is halted, failed to read %s: %w", c.ChainID, c.haltFile(), err)
+	}
+
+	var conflict ErrConflictingHeaders
+	if err = c.Cdc.UnmarshalJSON(bz, &conflict); err != nil {
+		return fmt.Errorf("chain %s is halted, failed to decode %s: %w", c.ChainID, c.haltFile(), err)
+	}
+	return &conflict
 }
 
-// InitLiteClient initializes the lite client for a given chain
-func (c *Chain) InitLiteClient(db *dbm.GoLevelDB, trustOpts lite.TrustOptions) (*lite.Client, error) {
-	httpProvider, err := litehttp.New(c.ChainID, c.RPCAddr)
+// InitLiteClient initializes the lite client for a given chain from trustOpts,
+// replacing any lite client the chain already holds
+func (c *Chain) InitLiteClient(trustOpts lite.TrustOptions) (*lite.Client, error) {
+	c.liteMtx.Lock()
+	defer c.liteMtx.Unlock()
+
+	db, err := c.openLiteDB()
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: provide actual witnesses!
-	lc, err := lite.NewClient(c.ChainID, trustOpts, httpProvider,
-		[]litep.Provider{httpProvider}, dbs.New(db, ""),
+	primary, witnesses := c.liteProviders()
+	lc, err := lite.NewClient(c.ChainID, trustOpts, primary,
+		witnesses, dbs.New(db, ""),
 		lite.Logger(log.NewTMLogger(log.NewSyncWriter(os.Stdout))))
 	if err != nil {
 		return nil, err
@@ -150,11 +365,12 @@ func (c *Chain) InitLiteClient(db *dbm.GoLevelDB, trustOpts lite.TrustOptions) (
 		return nil, err
 	}
 
+	c.lc = lc
 	return lc, nil
 }
 
 // TrustNodeInitClient trusts the configured node and initializes the lite client
-func (c *Chain) TrustNodeInitClient(db *dbm.GoLevelDB) (*lite.Client, error) {
+func (c *Chain) TrustNodeInitClient() (*lite.Client, error) {
 	// fetch latest height from configured node
 	height, err := c.QueryLatestHeight()
 	if err != nil {
@@ -168,7 +384,7 @@ func (c *Chain) TrustNodeInitClient(db *dbm.GoLevelDB) (*lite.Client, error) {
 	}
 
 	// initialize the lite client database
-	out, err := c.InitLiteClient(db, c.TrustOptions(height, header.Hash().Bytes()))
+	out, err := c.InitLiteClient(c.TrustOptions(height, header.Hash().Bytes()))
 	if err != nil {
 		return nil, err
 	}
@@ -176,27 +392,141 @@ func (c *Chain) TrustNodeInitClient(db *dbm.GoLevelDB) (*lite.Client, error) {
 	return out, nil
 }
 
-// NewLiteDB returns a new instance of the liteclient database connection
-// CONTRACT: must close the database connection when done with it (defer df())
-func (c *Chain) NewLiteDB() (db *dbm.GoLevelDB, df func(), err error) {
-	db, err = dbm.NewGoLevelDB(c.ChainID, liteDir(c.HomePath))
-	df = func() {
-		err := db.Close()
-		if err != nil {
-			panic(err)
-		}
+// openLiteDB returns the chain's lite client database, opening it on first use.
+// The database stays open until CloseLiteDB is called.
+func (c *Chain) openLiteDB() (*dbm.GoLevelDB, error) {
+	c.liteDBMtx.Lock()
+	defer c.liteDBMtx.Unlock()
+
+	if c.liteDB != nil {
+		return c.liteDB, nil
 	}
+
+	db, err := dbm.NewGoLevelDB(c.ChainID, liteDir(c.HomePath))
 	if err != nil {
-		return nil, nil, fmt.Errorf("can't open lite client database: %w", err)
+		return nil, fmt.Errorf("can't open lite client database: %w", err)
 	}
-	return
+
+	c.liteDB = db
+	return db, nil
+}
+
+// liteStore returns the signed header store backed by the chain's lite client database
+func (c *Chain) liteStore() (store.Store, error) {
+	db, err := c.openLiteDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return dbs.New(db, ""), nil
+}
+
+// CloseLiteDB drops the chain's lite client and closes its database, it is
+// reopened on next use
+func (c *Chain) CloseLiteDB() error {
+	c.liteMtx.Lock()
+	defer c.liteMtx.Unlock()
+
+	return c.closeLiteDB()
+}
+
+// CONTRACT: c.liteMtx must be held
+func (c *Chain) closeLiteDB() error {
+	c.lc = nil
+
+	c.liteDBMtx.Lock()
+	defer c.liteDBMtx.Unlock()
+
+	if c.liteDB == nil {
+		return nil
+	}
+
+	err := c.liteDB.Close()
+	c.liteDB = nil
+	return err
 }
 
 // DeleteLiteDB removes the lite client database on disk, forcing re-initialization
 func (c *Chain) DeleteLiteDB() error {
+	c.liteMtx.Lock()
+	defer c.liteMtx.Unlock()
+
+	if err := c.closeLiteDB(); err != nil {
+		return err
+	}
+
 	return os.RemoveAll(filepath.Join(liteDir(c.HomePath), fmt.Sprintf("%s.db", c.ChainID)))
 }
 
+// PruneLiteDB removes all but the most recent keep signed headers and their
+// validator sets from the lite client database and returns the number removed.
+// A keep of 0 or less leaves the database untouched.
+func (c *Chain) PruneLiteDB(keep int) (int, error) {
+	c.liteMtx.Lock()
+	defer c.liteMtx.Unlock()
+
+	db, err := c.openLiteDB()
+	if err != nil {
+		return 0, err
+	}
+
+	return pruneLiteStore(db, keep)
+}
+
+// pruneLiteStore deletes the oldest signed headers in the store until at most
+// keep of them are left
+func pruneLiteStore(db dbm.DB, keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+
+	heights, err := liteStoreHeights(db)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(heights) <= keep {
+		return 0, nil
+	}
+
+	store := dbs.New(db, "")
+	pruned := heights[:len(heights)-keep]
+	for _, h := range pruned {
+		if err = store.DeleteSignedHeaderAndNextValidatorSet(h); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(pruned), nil
+}
+
+// liteStoreHeights returns the heights of all signed headers in the store in
+// ascending order. The lite store keys signed headers as "sh/<prefix>/<height>"
+// with the height zero padded to 20 digits.
+func liteStoreHeights(db dbm.DB) ([]int64, error) {
+	itr, err := db.Iterator(liteSignedHeaderKey(1), liteSignedHeaderKey(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	var heights []int64
+	for ; itr.Valid(); itr.Next() {
+		key := string(itr.Key())
+		h, err := strconv.ParseInt(key[strings.LastIndex(key, "/")+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed lite store key %s: %w", key, err)
+		}
+		heights = append(heights, h)
+	}
+
+	return heights, nil
+}
+
+func liteSignedHeaderKey(height int64) []byte {
+	return []byte(fmt.Sprintf("sh//%020d", height))
+}
+
 // TrustOptions returns lite.TrustOptions given a height and hash
 func (c *Chain) TrustOptions(height int64, hash []byte) lite.TrustOptions {
 	return lite.TrustOptions{
@@ -241,10 +571,12 @@ func GetLatestHeaders(chains ...*Chain) (map[string]*tmclient.Header, error) {
 		wg.Add(1)
 		go func(hs *header, wg *sync.WaitGroup, chain *Chain) {
 			header, err := chain.GetLatestLiteHeader()
+			hs.Lock()
 			hs.Map[chain.ChainID] = header
 			if err != nil {
 				hs.Errs = append(hs.Errs, err)
 			}
+			hs.Unlock()
 			wg.Done()
 		}(hs, &wg, chain)
 	}
@@ -277,13 +609,11 @@ func (c *Chain) ValidateTxResult(resTx *ctypes.ResultTx) (err error) {
 
 // GetLatestLiteHeight uses the CLI utilities to pull the latest height from a given chain
 func (c *Chain) GetLatestLiteHeight() (int64, error) {
-	db, df, err := c.NewLiteDB()
+	store, err := c.liteStore()
 	if err != nil {
 		return -1, err
 	}
-	defer df()
 
-	store := dbs.New(db, "")
 	return store.LastSignedHeaderHeight()
 }
 
@@ -311,15 +641,10 @@ func GetLatestHeights(chains ...*Chain) (map[string]int64, error) {
 
 // GetLiteSignedHeaderAtHeight returns a signed header at a particular height
 func (c *Chain) GetLiteSignedHeaderAtHeight(height int64) (*tmclient.Header, error) {
-	// create database connection
-	db, df, err := c.NewLiteDB()
+	store, err := c.liteStore()
 	if err != nil {
 		return nil, err
 	}
-	defer df()
-
-	// QUESTION: Why do we need this store abstration here and not in other lite functions?
-	store := dbs.New(db, "")
 
 	// Fetch the signed header from the store
 	sh, err := store.SignedHeader(height)
@@ -336,4 +661,47 @@ func (c *Chain) GetLiteSignedHeaderAtHeight(height int64) (*tmclient.Header, err
 	return &tmclient.Header{SignedHeader: *sh, ValidatorSet: vs}, nil
 }
 
+// TrustedHeaderAtHeight returns the header at height as verified by the chain's
+// lite client, along with the validator set that signed it. Headers missing from
+// the lite database are fetched from the primary and verified.
+func (c *Chain) TrustedHeaderAtHeight(height int64) (*tmclient.Header, error) {
+	c.liteMtx.Lock()
+	defer c.liteMtx.Unlock()
+
+	lc, err := c.liteClient()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sh, err := lc.TrustedHeader(height, now)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := lc.TrustedValidatorSet(height, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tmclient.Header{SignedHeader: *sh, ValidatorSet: vs}, nil
+}
+
 var ErrLiteNotInitialized = errors.New("lite client is not initialized")
+
+// ErrConflictingHeaders is returned when a witness has a different header than
+// the primary at the same height. Either the chain has forked or one of the
+// nodes is lying, it carries both headers as evidence.
+type ErrConflictingHeaders struct {
+	ChainID       string                `json:"chain-id"`
+	Height        int64                 `json:"height"`
+	Primary       string                `json:"primary"`
+	Witness       string                `json:"witness"`
+	PrimaryHeader *tmtypes.SignedHeader `json:"primary-header"`
+	WitnessHeader *tmtypes.SignedHeader `json:"witness-header"`
+}
+
+func (e *ErrConflictingHeaders) Error() string {
+	return fmt.Sprintf("conflicting headers on chain %s at height %d: primary %s has %X, witness %s has %X",
+		e.ChainID, e.Height, e.Primary, e.PrimaryHeader.Hash(), e.Witness, e.WitnessHeader.Hash())
+}
@@ -0,0 +1,75 @@
+package relayer
+
+import (
+	"testing"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	dbs "github.com/tendermint/tendermint/lite2/store/db"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestPruneLiteStore(t *testing.T) {
+	vals := tmtypes.NewValidatorSet([]*tmtypes.Validator{tmtypes.NewValidator(ed25519.GenPrivKey().PubKey(), 1)})
+
+	cases := []struct {
+		name       string
+		keep       int
+		wantPruned int
+		wantFirst  int64
+	}{
+		{"keep the newest", 2, 3, 4},
+		{"keep one", 1, 4, 5},
+		{"keep all", 5, 0, 1},
+		{"keep more than stored", 10, 0, 1},
+		{"unbounded", 0, 0, 1},
+	}
+
+	for _, tc := range cases {
+		db := dbm.NewMemDB()
+		store := dbs.New(db, "")
+		for h := int64(1); h <= 5; h++ {
+			sh := &tmtypes.SignedHeader{Header: &tmtypes.Header{ChainID: "ibc0", Height: h}, Commit: &tmtypes.Commit{Height: h}}
+			if err := store.SaveSignedHeaderAndNextValidatorSet(sh, vals); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pruned, err := pruneLiteStore(db, tc.keep)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if pruned != tc.wantPruned {
+			t.Errorf("%s: pruned %d headers, want %d", tc.name, pruned, tc.wantPruned)
+		}
+
+		first, err := store.FirstSignedHeaderHeight()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last, err := store.LastSignedHeaderHeight()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != tc.wantFirst || last != 5 {
+			t.Errorf("%s: store holds heights %d to %d, want %d to 5", tc.name, first, last, tc.wantFirst)
+		}
+
+		// the validator sets of the pruned headers go with them
+		for h := int64(1); h <= 5; h++ {
+			_, shErr := store.SignedHeader(h)
+			_, vsErr := store.ValidatorSet(h + 1)
+			if kept := h >= tc.wantFirst; kept != (shErr == nil) || kept != (vsErr == nil) {
+				t.Errorf("%s: height %d kept %t, but signed header err %v, validator set err %v", tc.name, h, kept, shErr, vsErr)
+			}
+		}
+
+		heights, err := liteStoreHeights(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 5 - tc.wantPruned; len(heights) != want {
+			t.Errorf("%s: %d heights left, want %d", tc.name, len(heights), want)
+		}
+	}
+}